@@ -1,9 +1,15 @@
 // Package benchmark orchestrates concurrent query execution with worker pools and result collection.
 //
 // It implements a producer-consumer pattern where:
-//   - CSV parser distributes queries to worker-specific channels based on hostname affinity
-//   - Workers execute queries concurrently and send results to a collector
-//   - Result collector aggregates timing statistics
+//   - An input source, such as the CSV or JSON Lines parser, is distributed to worker-specific channels based on hostname affinity,
+//     either as fast as the workers take the records (closed loop), at a fixed arrival rate or
+//     replaying the original timing of the input (open loop)
+//   - Workers execute every query template for each record concurrently and send results to a collector,
+//     through verifiers checking the results off the timed path when verification is enabled
+//   - Result collector aggregates timing statistics, globally, per hostname, per worker and per stage
+//
+// The concurrency may follow a load profile whose stages run one after the other with
+// a different number of workers, to find the point where latency starts degrading.
 //
 // The package supports graceful shutdown through context cancellation and strict mode
 // for data validation. All workers respect context cancellation and will stop processing
@@ -13,135 +19,488 @@ package benchmark
 import (
 	"context"
 	"fmt"
-	"io"
 	"log"
+	"math/rand/v2"
 	"sync"
 	"time"
 
 	"github.com/sandinv/benchmark/internal/database"
+	"github.com/sandinv/benchmark/internal/metrics"
 	"github.com/sandinv/benchmark/internal/parser"
 	"github.com/sandinv/benchmark/internal/stats"
+	"github.com/sandinv/benchmark/internal/verify"
 )
 
 const workerChannelSize = 10
 
+// Config holds the benchmark runner settings
+type Config struct {
+	Workers int
+	// Stages is the load profile, changing the number of workers between stages.
+	// When empty the run has a single stage of Workers workers lasting until the input is exhausted.
+	Stages []Stage
+	// Rate is the number of records sent per second in open-loop runs, 0 runs a closed loop
+	// where each worker starts a record as soon as it is done with the previous one
+	Rate float64
+	// ReplaySpeed replays the input at its original timing, taken from the issue time
+	// of the records, sped up by the factor. 0 disables the replay, which overrides Rate.
+	ReplaySpeed float64
+	// ReplayInterval spaces the replayed records without an issue time, DefaultReplayInterval when 0
+	ReplayInterval time.Duration
+	// Warmup is the warm-up phase, whose queries are recorded apart from the statistics
+	Warmup Warmup
+	// Precision is the number of significant digits kept by the latency histograms,
+	// 0 stores every duration to compute exact statistics
+	Precision int
+	// Percentiles lists the percentiles to compute, stats.DefaultPercentiles when empty
+	Percentiles []float64
+	// Interval is the width of the timeline buckets, stats.DefaultInterval when 0
+	Interval time.Duration
+	// Progress is the interval between the progress lines logged during the run, 0 disables them
+	Progress time.Duration
+	// Input reports how much of the input the source read, for the progress lines. It may be nil.
+	Input InputProgress
+	// Metrics receives the live metrics of the run when not nil
+	Metrics *metrics.Registry
+	// ExplainRate is the share of the queries run with EXPLAIN ANALYZE to report their
+	// server-side execution, recorded apart from the statistics. 0 disables the sampling.
+	ExplainRate float64
+	// Verifier checks the result of every successful query when not nil, which
+	// requires the database to compute checksums
+	Verifier verify.Verifier
+	// Templates lists the queries run for every input record, database.DefaultTemplate when empty
+	Templates []database.Template
+}
+
+// queryRunner runs the queries of the workers, such as a database.Database
+type queryRunner interface {
+	Execute(ctx context.Context, template database.Template, params database.QueryParams) (database.Result, error)
+	Explain(ctx context.Context, template database.Template, params database.QueryParams) (database.Plan, error)
+	// MaxAttempts returns the number of attempts of each query allowed by the retry policy
+	MaxAttempts() int
+	// ConfigurePool sizes the connection pool for the given number of concurrent queries
+	ConfigurePool(connections int)
+}
+
 // Runner orchestrates the benchmark execution
 type Runner struct {
-	db         *database.Database
-	workers    int
-	strictMode bool
+	queries        queryRunner
+	stages         []Stage
+	rate           float64
+	replaySpeed    float64
+	replayInterval time.Duration
+	warmup         Warmup
+	precision      int
+	percentiles    []float64
+	interval       time.Duration
+	progress       time.Duration
+	input          InputProgress
+	metrics        *metrics.Registry
+	explainRate    float64
+	verifier       verify.Verifier
+	templates      []database.Template
 }
 
 // NewRunner creates a new benchmark runner
-func NewRunner(db *database.Database, workers int, strictMode bool) *Runner {
-	db.ConfigurePool(workers)
+func NewRunner(db *database.Database, config Config) *Runner {
+	return newRunner(db, config)
+}
+
+// newRunner creates a benchmark runner running the queries with the query runner
+func newRunner(queries queryRunner, config Config) *Runner {
+	stages := config.Stages
+	if len(stages) == 0 {
+		stages = []Stage{{Workers: config.Workers}}
+	}
+
+	// The reference queries are run by one verifier per worker, which need connections of their
+	// own not to make the workers wait for one within the timed query
+	connections := maxWorkers(stages)
+	if _, ok := config.Verifier.(*verify.Reference); ok {
+		connections *= 2
+	}
+	queries.ConfigurePool(connections)
+
+	templates := config.Templates
+	if len(templates) == 0 {
+		templates = []database.Template{database.DefaultTemplate}
+	}
+
 	return &Runner{
-		db:         db,
-		workers:    workers,
-		strictMode: strictMode,
+		queries:        queries,
+		stages:         stages,
+		rate:           config.Rate,
+		replaySpeed:    config.ReplaySpeed,
+		replayInterval: config.ReplayInterval,
+		warmup:         config.Warmup,
+		precision:      config.Precision,
+		percentiles:    config.Percentiles,
+		interval:       config.Interval,
+		progress:       config.Progress,
+		input:          config.Input,
+		metrics:        config.Metrics,
+		explainRate:    config.ExplainRate,
+		verifier:       config.Verifier,
+		templates:      templates,
 	}
 }
 
-// Run executes the benchmark and returns statistics
-func (r *Runner) Run(ctx context.Context, input io.Reader) (*stats.Statistics, error) {
+// Run executes the benchmark over the records of the source and returns statistics.
+// The stages of the load profile run one after the other, each one with its own workers:
+// a stage stops taking records once its duration has elapsed and ends when its workers
+// are done with the records they were assigned.
+func (r *Runner) Run(ctx context.Context, source parser.Source) (*stats.Statistics, error) {
 
-	statistics := stats.New()
+	statistics := stats.NewWithPrecision(r.precision)
+	if len(r.percentiles) > 0 {
+		statistics.ReportedPercentiles = r.percentiles
+	}
+	if r.interval > 0 {
+		statistics.Interval = r.interval
+	}
 	startTime := time.Now()
-
-	// Create worker-specific channels (one per worker for hostname affinity)
-	workerChannels := make([]chan database.QueryParams, r.workers)
-	for i := 0; i < r.workers; i++ {
-		workerChannels[i] = make(chan database.QueryParams, workerChannelSize)
+	statistics.Metadata.Workers = maxWorkers(r.stages)
+	statistics.Metadata.StartTime = startTime
+	switch {
+	case r.replaySpeed > 0:
+		statistics.SetReplaySpeed(r.replaySpeed)
+	case r.rate > 0:
+		statistics.SetTargetRate(r.rate)
+	}
+	if attempts := r.queries.MaxAttempts(); attempts > 1 {
+		statistics.SetMaxAttempts(attempts)
+	}
+	if r.explainRate > 0 {
+		statistics.SetExplainRate(r.explainRate)
+	}
+	if r.verifier != nil && r.verifier.Method() != "" {
+		statistics.SetVerification(r.verifier.Method())
 	}
 
 	// Create results channel
 	results := make(chan result, workerChannelSize)
 
-	// Start workers
-	var workerWg sync.WaitGroup
-	for i := 0; i < r.workers; i++ {
-		workerWg.Go(func() {
-			r.worker(ctx, workerChannels[i], results)
-		})
-	}
-
 	// Start result collector
 	var collectorWg sync.WaitGroup
+	warmup := newWarmupTracker(r.warmup, startTime)
+	var progress *progressReporter
+	if r.progress > 0 {
+		progress = newProgressReporter(startTime, r.input, runEnd(startTime, source, r.stages))
+	}
 	collectorWg.Go(func() {
-		r.collectResults(results, statistics)
+		r.collectResults(results, statistics, warmup, progress)
 	})
 
-	// Parse CSV and distribute queries to workers based on hostname
-	csvParser := parser.NewCSVParser(input, r.strictMode)
-	if err := csvParser.ParseAndDistribute(ctx, workerChannels); err != nil {
-		if r.strictMode {
-			// In strict mode, return the error immediately
-			return nil, fmt.Errorf("CSV parsing error: %w", err)
+	// Start the verifiers, one per worker, between the workers and the collector
+	var verifications chan verification
+	var verifierWg sync.WaitGroup
+	if r.verifier != nil {
+		workers := maxWorkers(r.stages)
+		verifications = make(chan verification, workers*workerChannelSize)
+		for range workers {
+			verifierWg.Go(func() {
+				r.verifyResults(ctx, verifications, results)
+			})
 		}
-		log.Printf("Error parsing CSV: %v", err)
 	}
 
-	// Close all worker channels and wait for workers
-	for i := 0; i < r.workers; i++ {
-		close(workerChannels[i])
+	dispatcher := r.newDispatcher(source)
+	for i, stage := range r.stages {
+		exhausted, err := r.runStage(ctx, i, stage, dispatcher, verifications, results, statistics)
+		if err != nil {
+			if source.Strict() {
+				// In strict mode, return the error immediately
+				return nil, fmt.Errorf("input parsing error: %w", err)
+			}
+			log.Printf("Error parsing input: %v", err)
+			break
+		}
+		if exhausted {
+			if i < len(r.stages)-1 {
+				log.Printf("Input exhausted during stage %d of %d", i+1, len(r.stages))
+			}
+			break
+		}
 	}
-	workerWg.Wait()
 
-	// Close results channel and wait for collector
+	// Wait for the pending verifications, then close results channel and wait for collector
+	if verifications != nil {
+		close(verifications)
+		verifierWg.Wait()
+	}
 	close(results)
 	collectorWg.Wait()
 
 	// Finalize statistics by calculating the processing time and the stats
-	statistics.ProcessingTime = time.Since(startTime)
+	statistics.Metadata.EndTime = time.Now()
+	statistics.ProcessingTime = statistics.Metadata.EndTime.Sub(startTime)
 	statistics.Compute()
 
 	return statistics, nil
 }
 
+// runStage starts the workers of the stage, dispatches records to them for the duration of
+// the stage and waits for them to finish. It reports whether the source was exhausted.
+func (r *Runner) runStage(ctx context.Context, index int, stage Stage, dispatcher *dispatcher, verifications chan<- verification, results chan<- result, statistics *stats.Statistics) (bool, error) {
+	stageStart := time.Now()
+	var deadline time.Time
+	if stage.Duration > 0 {
+		deadline = stageStart.Add(stage.Duration)
+	}
+
+	// Create worker-specific channels (one per worker for hostname affinity)
+	workerChannels := make([]chan job, stage.Workers)
+	for i := 0; i < stage.Workers; i++ {
+		workerChannels[i] = make(chan job, workerChannelSize)
+	}
+
+	// Start workers
+	var workerWg sync.WaitGroup
+	for i := 0; i < stage.Workers; i++ {
+		workerWg.Go(func() {
+			busy, idle := r.worker(ctx, i, index, workerChannels[i], verifications, results)
+			statistics.RecordWorkerTime(i, busy, idle)
+		})
+	}
+
+	// Read the source and distribute queries to workers based on hostname
+	exhausted, err := dispatcher.dispatch(ctx, workerChannels, deadline)
+
+	// Close all worker channels and wait for workers
+	for i := 0; i < stage.Workers; i++ {
+		close(workerChannels[i])
+	}
+	workerWg.Wait()
+
+	statistics.RecordStage(index, stage.Workers, time.Since(stageStart))
+	return exhausted, err
+}
+
 // result represents the outcome of a single query execution
 type result struct {
+	Hostname string
+	Template string
+	WorkerID int
+	Stage    int
+	Start    time.Time
+	End      time.Time
 	Duration time.Duration
+	Rows     int64
+	Bytes    int64
+	// FirstRow is the time to the first row and Fetch the time spent fetching the rows after it
+	FirstRow time.Duration
+	Fetch    time.Duration
 	Error    error
+	// Attempts is the number of times the query was run and FirstAttempt the latency of the first one
+	Attempts     int
+	FirstAttempt time.Duration
+	// Explained marks the queries run with EXPLAIN ANALYZE and Plan holds their server-side statistics
+	Explained bool
+	Plan      database.Plan
+	// Verdict is the outcome of the verification of the result and VerdictDetail describes
+	// a mismatch or why the expected result is unknown
+	Verdict       stats.Verdict
+	VerdictDetail string
+	// Lag is the delay between the scheduled and actual start of the record in
+	// open-loop runs, reported by the first template of each record only
+	Lag       time.Duration
+	Scheduled bool
 }
 
-// worker processes queries from the channel
-func (r *Runner) worker(ctx context.Context, queries <-chan database.QueryParams, results chan<- result) {
+// verification is a successful query result waiting for the verdict of the verifier
+type verification struct {
+	res      result
+	template database.Template
+	params   database.QueryParams
+	result   database.Result
+}
+
+// worker processes queries from the channel and returns the time it spent
+// executing queries (busy) and waiting for queries to be assigned (idle).
+// The latency of a query scheduled in an open-loop run is measured from the time
+// it was due: the scheduled time of its record for the first template and the end
+// of the previous template for the others, so that queuing delays are accounted for.
+// The results to verify are sent to the verifications channel, the others to the collector.
+func (r *Runner) worker(ctx context.Context, id, stage int, queries <-chan job, verifications chan<- verification, results chan<- result) (busy, idle time.Duration) {
+	waitStart := time.Now()
 	for {
 		select {
 		case <-ctx.Done():
 			// Context cancelled, exit gracefully
-			return
-		case params, ok := <-queries:
+			return busy, idle + time.Since(waitStart)
+		case next, ok := <-queries:
+			idle += time.Since(waitStart)
 			if !ok {
 				// Channel closed, exit gracefully
-				return
+				return busy, idle
 			}
 
-			start := time.Now()
-			err := r.db.Execute(ctx, params)
-			duration := time.Since(start)
+			// Every template is run for the query parameters, one after the other
+			due := next.scheduled
+			for i, template := range r.templates {
+				if r.metrics != nil {
+					r.metrics.QueryStarted(id)
+				}
+				explained := r.explainRate > 0 && rand.Float64() < r.explainRate
+				var (
+					queryResult database.Result
+					plan        database.Plan
+					err         error
+				)
+				start := time.Now()
+				if explained {
+					plan, err = r.queries.Explain(ctx, template, next.params)
+				} else {
+					queryResult, err = r.queries.Execute(ctx, template, next.params)
+				}
+				end := time.Now()
+				if r.metrics != nil {
+					r.metrics.QueryFinished(id)
+				}
+				busy += end.Sub(start)
 
-			// Try to send result, but respect context cancellation
-			select {
-			case <-ctx.Done():
+				res := result{
+					Hostname:     next.params.Hostname,
+					Template:     template.Name,
+					WorkerID:     id,
+					Stage:        stage,
+					Start:        start,
+					End:          end,
+					Duration:     end.Sub(start),
+					Rows:         queryResult.Rows,
+					Bytes:        queryResult.Bytes,
+					FirstRow:     queryResult.FirstRow,
+					Fetch:        queryResult.Total - queryResult.FirstRow,
+					Error:        err,
+					Attempts:     queryResult.Attempts,
+					FirstAttempt: queryResult.FirstAttempt,
+					Explained:    explained,
+					Plan:         plan,
+				}
+				if !due.IsZero() {
+					res.Duration = end.Sub(due)
+					res.FirstAttempt = start.Add(queryResult.FirstAttempt).Sub(due)
+					if i == 0 {
+						res.Lag, res.Scheduled = start.Sub(due), true
+					}
+					due = end
+				}
+
+				// Results are verified by the verifiers, leaving the verification time out of
+				// the latency, the worker times and the schedule of the next template
+				if r.verifier != nil && err == nil && !explained {
+					select {
+					case <-ctx.Done():
+						return busy, idle
+					case verifications <- verification{res: res, template: template, params: next.params, result: queryResult}:
+					}
+					continue
+				}
+
+				// Try to send result, but respect context cancellation
+				select {
+				case <-ctx.Done():
+					return busy, idle
+				case results <- res:
+				}
+			}
+			waitStart = time.Now()
+		}
+	}
+}
+
+// verifyResults verifies the results sent by the workers and passes them on to the collector
+func (r *Runner) verifyResults(ctx context.Context, verifications <-chan verification, results chan<- result) {
+	for v := range verifications {
+		v.res.Verdict, v.res.VerdictDetail = r.verifier.Verify(ctx, v.template, v.params, v.result)
+
+		// Keep draining the verifications once cancelled so that no worker is left blocked
+		select {
+		case <-ctx.Done():
+		case results <- v.res:
+		}
+	}
+}
+
+// collectResults aggregates query results, logging a progress line every progress interval
+// when a progress reporter is given
+func (r *Runner) collectResults(results <-chan result, statistics *stats.Statistics, warmup *warmupTracker, progress *progressReporter) {
+	var ticks <-chan time.Time
+	if progress != nil {
+		ticker := time.NewTicker(r.progress)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	for {
+		select {
+		case now := <-ticks:
+			log.Print(progress.report(now))
+		case res, ok := <-results:
+			if !ok {
 				return
-			case results <- result{
-				Duration: duration,
-				Error:    err,
-			}:
+			}
+			measured := r.collectResult(res, statistics, warmup)
+			if progress != nil {
+				progress.add(res, measured)
 			}
 		}
 	}
 }
 
-// collectResults aggregates query results
-func (r *Runner) collectResults(results <-chan result, statistics *stats.Statistics) {
-	for res := range results {
-		if res.Error != nil {
-			log.Printf("Query error: %v", res.Error)
-			statistics.RecordError()
-		} else {
-			statistics.Record(res.Duration)
+// collectResult adds the result of a query to the statistics and the metrics. It reports
+// whether the query is measured, as opposed to the warm-up and EXPLAIN ANALYZE queries.
+func (r *Runner) collectResult(res result, statistics *stats.Statistics, warmup *warmupTracker) bool {
+	var errorClass string
+	if res.Error != nil {
+		errorClass = database.ErrorClass(res.Error)
+		log.Printf("Query error (%s): %v", errorClass, res.Error)
+	}
+	if r.metrics != nil {
+		switch {
+		case res.Error != nil:
+			r.metrics.ObserveError(res.Template, errorClass)
+		case res.Explained:
+			// EXPLAIN ANALYZE changes the latency and doesn't return the rows
+			r.metrics.ObserveUntimed(res.Template)
+		default:
+			r.metrics.ObserveQuery(res.Template, res.Duration, res.Rows)
 		}
 	}
+	if res.Verdict == stats.VerdictMismatch {
+		log.Printf("Result mismatch for %s (%s): %s", res.Hostname, res.Template, res.VerdictDetail)
+	}
+	// The sampled queries are recorded apart and don't use up the warm-up queries
+	var isWarmup bool
+	if res.Explained {
+		isWarmup = warmup.inProgress(res.Start)
+	} else {
+		isWarmup = warmup.isWarmup(res.Start)
+	}
+	if res.Scheduled && !isWarmup {
+		statistics.RecordScheduleLag(res.Lag)
+	}
+	statistics.Add(stats.Sample{
+		Hostname:      res.Hostname,
+		Template:      res.Template,
+		WorkerID:      res.WorkerID,
+		Stage:         res.Stage,
+		End:           res.End,
+		Duration:      res.Duration,
+		Rows:          res.Rows,
+		Bytes:         res.Bytes,
+		FirstRow:      res.FirstRow,
+		Fetch:         res.Fetch,
+		Err:           res.Error,
+		ErrorClass:    errorClass,
+		TimedOut:      res.Error != nil && database.IsTimeout(res.Error),
+		Attempts:      res.Attempts,
+		FirstAttempt:  res.FirstAttempt,
+		Warmup:        isWarmup,
+		Explained:     res.Explained,
+		Plan:          stats.Plan(res.Plan),
+		Verdict:       res.Verdict,
+		VerdictDetail: res.VerdictDetail,
+	})
+	return !isWarmup && !res.Explained
 }
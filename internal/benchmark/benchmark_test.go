@@ -0,0 +1,148 @@
+package benchmark
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sandinv/benchmark/internal/database"
+	"github.com/sandinv/benchmark/internal/stats"
+	"github.com/sandinv/benchmark/internal/verify"
+)
+
+// fastQueries returns an empty result to every query without delay
+type fastQueries struct{}
+
+func (fastQueries) Execute(context.Context, database.Template, database.QueryParams) (database.Result, error) {
+	return database.Result{Rows: 1, Attempts: 1}, nil
+}
+
+func (fastQueries) Explain(context.Context, database.Template, database.QueryParams) (database.Plan, error) {
+	return database.Plan{}, nil
+}
+
+func (fastQueries) MaxAttempts() int {
+	return 1
+}
+
+func (fastQueries) ConfigurePool(int) {}
+
+// pooledQueries runs the queries on a bounded number of connections, taking delays[sql]
+// to run a query once it holds a connection
+type pooledQueries struct {
+	connections chan struct{}
+	delays      map[string]time.Duration
+}
+
+func (p *pooledQueries) Execute(ctx context.Context, template database.Template, _ database.QueryParams) (database.Result, error) {
+	select {
+	case p.connections <- struct{}{}:
+	case <-ctx.Done():
+		return database.Result{}, ctx.Err()
+	}
+	defer func() { <-p.connections }()
+
+	time.Sleep(p.delays[template.SQL])
+	return database.Result{Rows: 1, Attempts: 1}, nil
+}
+
+func (p *pooledQueries) Explain(context.Context, database.Template, database.QueryParams) (database.Plan, error) {
+	return database.Plan{}, nil
+}
+
+func (p *pooledQueries) MaxAttempts() int {
+	return 1
+}
+
+func (p *pooledQueries) ConfigurePool(connections int) {
+	p.connections = make(chan struct{}, connections)
+}
+
+// slowVerifier takes the given time to match every result
+type slowVerifier struct {
+	delay time.Duration
+}
+
+func (v slowVerifier) Verify(context.Context, database.Template, database.QueryParams, database.Result) (stats.Verdict, string) {
+	time.Sleep(v.delay)
+	return stats.VerdictMatch, ""
+}
+
+func (slowVerifier) Method() string {
+	return "reference queries"
+}
+
+func TestWorkerVerificationOffTimedPath(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	r := &Runner{
+		queries:   fastQueries{},
+		verifier:  slowVerifier{delay: delay},
+		templates: []database.Template{{Name: "first"}, {Name: "second"}},
+	}
+	ctx := context.Background()
+
+	// An open-loop record, so that the second template is due when the first one ends
+	queries := make(chan job, 1)
+	queries <- job{params: database.QueryParams{Hostname: "host_000001"}, scheduled: time.Now()}
+	close(queries)
+
+	verifications := make(chan verification, workerChannelSize)
+	results := make(chan result, workerChannelSize)
+	var verifierWg sync.WaitGroup
+	verifierWg.Go(func() {
+		r.verifyResults(ctx, verifications, results)
+	})
+
+	busy, _ := r.worker(ctx, 0, 0, queries, verifications, results)
+	close(verifications)
+	verifierWg.Wait()
+	close(results)
+
+	if busy >= delay {
+		t.Errorf("Expected the busy time to leave out the verification, got %v", busy)
+	}
+	received := 0
+	for res := range results {
+		received++
+		if res.Verdict != stats.VerdictMatch {
+			t.Errorf("Expected the %s result to be verified, got verdict %v", res.Template, res.Verdict)
+		}
+		if res.Duration >= delay {
+			t.Errorf("Expected the %s latency to leave out the verification, got %v", res.Template, res.Duration)
+		}
+	}
+	if received != 2 {
+		t.Errorf("Expected 2 results, got %d", received)
+	}
+}
+
+func TestRunReferenceConnections(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	reference := database.Template{Name: database.DefaultTemplate.Name, SQL: "reference", Args: database.DefaultTemplate.Args}
+	queries := &pooledQueries{delays: map[string]time.Duration{
+		database.DefaultTemplate.SQL: 10 * time.Millisecond,
+		reference.SQL:                delay,
+	}}
+	verifier, err := verify.NewReference(queries, []database.Template{database.DefaultTemplate}, []database.Template{reference})
+	if err != nil {
+		t.Fatalf("NewReference() failed: %v", err)
+	}
+
+	r := newRunner(queries, Config{Workers: 1, Verifier: verifier})
+	if cap(queries.connections) != 2 {
+		t.Errorf("Expected a connection for the worker and one for the verifier, got %d", cap(queries.connections))
+	}
+
+	// The worker never waits for the connection held by the reference queries
+	statistics, err := r.Run(context.Background(), newCSVSource(t, 5))
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if statistics.TotalQueries != 5 || statistics.Verification.Checked != 5 {
+		t.Errorf("Expected 5 verified queries, got %d queries and %+v", statistics.TotalQueries, statistics.Verification)
+	}
+	if statistics.MaxTime >= delay/2 {
+		t.Errorf("Expected the latency to leave out the reference queries, got a maximum of %v", statistics.MaxTime)
+	}
+}
@@ -0,0 +1,184 @@
+package benchmark
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/sandinv/benchmark/internal/database"
+	"github.com/sandinv/benchmark/internal/parser"
+)
+
+// DefaultReplayInterval is the spacing of replayed records without an issue time
+const DefaultReplayInterval = time.Second
+
+// job is a record assigned to a worker
+type job struct {
+	params database.QueryParams
+	// scheduled is the time the record was due to be sent in open-loop runs, zero otherwise
+	scheduled time.Time
+}
+
+// pacer computes the time each record is due to be sent in open-loop runs
+type pacer interface {
+	// due returns the time the next record is due, given its parameters
+	due(params database.QueryParams) time.Time
+}
+
+// ratePacer schedules the records at a fixed arrival rate, each one being due at a
+// fixed offset from the start of the run, whether or not the previous ones were on time
+type ratePacer struct {
+	start    time.Time
+	interval time.Duration
+	records  int
+}
+
+func (p *ratePacer) due(_ database.QueryParams) time.Time {
+	due := p.start.Add(time.Duration(p.records) * p.interval)
+	p.records++
+	return due
+}
+
+// replayPacer schedules the records at their original offsets, taken from their issue
+// time and divided by the speed. Records without an issue time are spaced by the
+// interval from the previous one, and records issued before the previous one, as in
+// unsorted inputs, are due at the same time as it.
+type replayPacer struct {
+	speed    float64
+	interval time.Duration
+
+	previous time.Time // time the previous record was due, zero for the first record
+	origin   time.Time // issue time of the first record that had one
+	originAt time.Time // time that record was due
+}
+
+func (p *replayPacer) due(params database.QueryParams) time.Time {
+	due := p.previous
+	if due.IsZero() {
+		due = time.Now()
+	} else {
+		due = due.Add(p.scale(p.interval))
+	}
+
+	if !params.IssueTime.IsZero() {
+		if p.origin.IsZero() {
+			p.origin, p.originAt = params.IssueTime, due
+		}
+		due = p.originAt.Add(p.scale(params.IssueTime.Sub(p.origin)))
+		if due.Before(p.previous) {
+			due = p.previous
+		}
+	}
+
+	p.previous = due
+	return due
+}
+
+// scale converts an offset of the original traffic to the replay speed
+func (p *replayPacer) scale(d time.Duration) time.Duration {
+	return time.Duration(float64(d) / p.speed)
+}
+
+// newPacer returns the pacer of open-loop runs, nil for closed-loop runs
+func (r *Runner) newPacer() pacer {
+	switch {
+	case r.replaySpeed > 0:
+		interval := r.replayInterval
+		if interval <= 0 {
+			interval = DefaultReplayInterval
+		}
+		return &replayPacer{speed: r.replaySpeed, interval: interval}
+	case r.rate > 0:
+		return &ratePacer{start: time.Now(), interval: time.Duration(float64(time.Second) / r.rate)}
+	default:
+		return nil
+	}
+}
+
+// dispatcher sends the records of the source to the workers, stage after stage
+type dispatcher struct {
+	source parser.Source
+	pacer  pacer // nil in closed-loop runs
+	// pending is a record read but not yet sent, as it was due after the end of the previous stage
+	pending *job
+}
+
+// newDispatcher creates the dispatcher of the source for the run
+func (r *Runner) newDispatcher(source parser.Source) *dispatcher {
+	return &dispatcher{source: source, pacer: r.newPacer()}
+}
+
+// dispatch reads the records of the source and sends them to the worker of their hostname
+// until the deadline, if any, and reports whether the source was exhausted.
+// In open-loop runs each record is held back until the time it is due.
+func (d *dispatcher) dispatch(ctx context.Context, workerChannels []chan job, deadline time.Time) (bool, error) {
+	for {
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return false, nil
+		}
+
+		next, err := d.next(ctx)
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		if !next.scheduled.IsZero() {
+			// Records due after the deadline are kept for the next stage
+			if !deadline.IsZero() && next.scheduled.After(deadline) {
+				d.pending = &next
+				return false, sleepUntil(ctx, deadline)
+			}
+			if err := sleepUntil(ctx, next.scheduled); err != nil {
+				return false, err
+			}
+		}
+
+		// Try to send to worker channel, but respect context cancellation
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case workerChannels[parser.WorkerIndex(next.params.Hostname, len(workerChannels))] <- next:
+		}
+	}
+}
+
+// next returns the pending record or reads the next one and schedules it
+func (d *dispatcher) next(ctx context.Context) (job, error) {
+	if d.pending != nil {
+		next := *d.pending
+		d.pending = nil
+		return next, nil
+	}
+
+	params, err := parser.NextRecord(ctx, d.source)
+	if err != nil {
+		return job{}, err
+	}
+
+	next := job{params: params}
+	if d.pacer != nil {
+		next.scheduled = d.pacer.due(params)
+	}
+	return next, nil
+}
+
+// sleepUntil waits until the given time or the context is cancelled
+func sleepUntil(ctx context.Context, t time.Time) error {
+	delay := time.Until(t)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
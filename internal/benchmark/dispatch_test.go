@@ -0,0 +1,181 @@
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sandinv/benchmark/internal/database"
+	"github.com/sandinv/benchmark/internal/parser"
+)
+
+// newCSVSource returns a CSV source of n records spread over a few hostnames
+func newCSVSource(t *testing.T, n int) parser.Source {
+	t.Helper()
+
+	var csv strings.Builder
+	csv.WriteString("hostname,start_time,end_time\n")
+	for i := 0; i < n; i++ {
+		_, _ = fmt.Fprintf(&csv, "host_%06d,2017-01-01 08:59:22,2017-01-01 09:59:22\n", i%5)
+	}
+
+	source, err := parser.NewSource(parser.FormatCSV, strings.NewReader(csv.String()), true, nil)
+	if err != nil {
+		t.Fatalf("NewSource() failed: %v", err)
+	}
+	return source
+}
+
+// runDispatch dispatches the source to the workers and returns the jobs received
+func runDispatch(t *testing.T, r *Runner, source parser.Source, workers int) []job {
+	t.Helper()
+
+	workerChannels := make([]chan job, workers)
+	for i := range workerChannels {
+		workerChannels[i] = make(chan job, 100)
+	}
+
+	exhausted, err := r.newDispatcher(source).dispatch(context.Background(), workerChannels, time.Time{})
+	if err != nil {
+		t.Fatalf("dispatch() failed: %v", err)
+	}
+	if !exhausted {
+		t.Error("Expected the source to be exhausted")
+	}
+
+	var jobs []job
+	for i, ch := range workerChannels {
+		close(ch)
+		for j := range ch {
+			if want := parser.WorkerIndex(j.params.Hostname, workers); want != i {
+				t.Errorf("Hostname %q sent to worker %d, expected %d", j.params.Hostname, i, want)
+			}
+			jobs = append(jobs, j)
+		}
+	}
+	return jobs
+}
+
+func TestDispatchClosedLoop(t *testing.T) {
+	r := &Runner{}
+
+	jobs := runDispatch(t, r, newCSVSource(t, 20), 3)
+	if len(jobs) != 20 {
+		t.Fatalf("Expected 20 jobs, got %d", len(jobs))
+	}
+	for _, j := range jobs {
+		if !j.scheduled.IsZero() {
+			t.Errorf("Expected no scheduled time in a closed loop, got %v", j.scheduled)
+		}
+	}
+}
+
+func TestDispatchOpenLoop(t *testing.T) {
+	r := &Runner{rate: 1000}
+
+	start := time.Now()
+	jobs := runDispatch(t, r, newCSVSource(t, 20), 3)
+	elapsed := time.Since(start)
+
+	if len(jobs) != 20 {
+		t.Fatalf("Expected 20 jobs, got %d", len(jobs))
+	}
+
+	// Records are due every millisecond from the start of the dispatch
+	first := jobs[0].scheduled
+	for _, j := range jobs {
+		if j.scheduled.Before(first) {
+			first = j.scheduled
+		}
+	}
+	for _, j := range jobs {
+		offset := j.scheduled.Sub(first)
+		if offset%time.Millisecond != 0 || offset > 19*time.Millisecond {
+			t.Errorf("Unexpected schedule offset %v", offset)
+		}
+	}
+	if elapsed < 19*time.Millisecond {
+		t.Errorf("Expected the dispatch to be paced over at least 19ms, took %v", elapsed)
+	}
+}
+
+func TestReplayPacer(t *testing.T) {
+	at := func(seconds int) database.QueryParams {
+		return database.QueryParams{IssueTime: time.Date(2017, 1, 1, 10, 0, seconds, 0, time.UTC)}
+	}
+
+	// Original offsets are halved at 2x, an earlier issue time is due with the previous
+	// record and a record without issue time is due one scaled interval later
+	pacer := &replayPacer{speed: 2, interval: time.Second}
+	records := []database.QueryParams{at(0), at(4), at(10), at(6), {}, at(12)}
+	want := []time.Duration{0, 2 * time.Second, 5 * time.Second, 5 * time.Second, 5500 * time.Millisecond, 6 * time.Second}
+
+	first := pacer.due(records[0])
+	for i, params := range records[1:] {
+		if got := pacer.due(params).Sub(first); got != want[i+1] {
+			t.Errorf("Record %d due at %v, expected %v", i+1, got, want[i+1])
+		}
+	}
+}
+
+func TestReplayPacerWithoutIssueTimes(t *testing.T) {
+	// Records are spaced by the interval in row order
+	pacer := &replayPacer{speed: 10, interval: time.Second}
+
+	first := pacer.due(database.QueryParams{})
+	for i := 1; i <= 3; i++ {
+		want := time.Duration(i) * 100 * time.Millisecond
+		if got := pacer.due(database.QueryParams{}).Sub(first); got != want {
+			t.Errorf("Record %d due at %v, expected %v", i, got, want)
+		}
+	}
+}
+
+func TestDispatchReplay(t *testing.T) {
+	r := &Runner{replaySpeed: 100}
+
+	var csv strings.Builder
+	csv.WriteString("issue_time,hostname,start_time,end_time\n")
+	for i := 0; i < 5; i++ {
+		// One second apart, replayed 10ms apart
+		_, _ = fmt.Fprintf(&csv, "2017-01-01 10:00:0%d,host_%06d,2017-01-01 08:59:22,2017-01-01 09:59:22\n", i, i)
+	}
+	source, err := parser.NewSource(parser.FormatCSV, strings.NewReader(csv.String()), true, nil)
+	if err != nil {
+		t.Fatalf("NewSource() failed: %v", err)
+	}
+
+	start := time.Now()
+	jobs := runDispatch(t, r, source, 2)
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Expected the replay to take at least 40ms, took %v", elapsed)
+	}
+	if len(jobs) != 5 {
+		t.Errorf("Expected 5 jobs, got %d", len(jobs))
+	}
+}
+
+func TestDispatchDeadline(t *testing.T) {
+	// At 100 records/s, at most the 10 records due before the 95ms deadline are sent
+	// and the following ones are left for the next stage
+	d := (&Runner{rate: 100}).newDispatcher(newCSVSource(t, 20))
+	workerChannels := []chan job{make(chan job, 100)}
+
+	exhausted, err := d.dispatch(context.Background(), workerChannels, time.Now().Add(95*time.Millisecond))
+	if err != nil || exhausted {
+		t.Fatalf("dispatch() = %v, %v, expected the deadline to stop it", exhausted, err)
+	}
+	if got := len(workerChannels[0]); got == 0 || got > 10 {
+		t.Errorf("Expected up to 10 records before the deadline, got %d", got)
+	}
+
+	exhausted, err = d.dispatch(context.Background(), workerChannels, time.Time{})
+	if err != nil || !exhausted {
+		t.Fatalf("dispatch() = %v, %v, expected the source to be exhausted", exhausted, err)
+	}
+	if got := len(workerChannels[0]); got != 20 {
+		t.Errorf("Expected 20 records in total, got %d", got)
+	}
+}
@@ -0,0 +1,94 @@
+package benchmark
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stage is a step of a load profile, running the given number of workers for a duration
+type Stage struct {
+	Workers int
+	// Duration of the stage, 0 runs it until the input is exhausted (last stage only)
+	Duration time.Duration
+}
+
+// ParseProfile parses a load profile made of comma separated stages written as
+// workers:duration, e.g. "2:30s,4:30s,8:30s,16:30s". The duration of the last stage
+// may be omitted to run it until the input is exhausted.
+func ParseProfile(spec string) ([]Stage, error) {
+	var stages []Stage
+	for field := range strings.SplitSeq(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		stage, err := parseStage(field)
+		if err != nil {
+			return nil, fmt.Errorf("stage %d: %w", len(stages)+1, err)
+		}
+		stages = append(stages, stage)
+	}
+
+	if len(stages) == 0 {
+		return nil, fmt.Errorf("empty load profile")
+	}
+	for i, stage := range stages[:len(stages)-1] {
+		if stage.Duration == 0 {
+			return nil, fmt.Errorf("stage %d: only the last stage may omit its duration", i+1)
+		}
+	}
+
+	return stages, nil
+}
+
+// ReadProfile reads a load profile with one stage per line in the ParseProfile format.
+// Empty lines and lines starting with # are ignored.
+func ReadProfile(input io.Reader) ([]Stage, error) {
+	var fields []string
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields = append(fields, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return ParseProfile(strings.Join(fields, ","))
+}
+
+// parseStage parses a single workers:duration stage
+func parseStage(field string) (Stage, error) {
+	workersField, durationField, hasDuration := strings.Cut(field, ":")
+
+	workers, err := strconv.Atoi(strings.TrimSpace(workersField))
+	if err != nil || workers <= 0 {
+		return Stage{}, fmt.Errorf("invalid number of workers %q", workersField)
+	}
+
+	stage := Stage{Workers: workers}
+	if hasDuration {
+		if stage.Duration, err = time.ParseDuration(strings.TrimSpace(durationField)); err != nil || stage.Duration <= 0 {
+			return Stage{}, fmt.Errorf("invalid duration %q", durationField)
+		}
+	}
+
+	return stage, nil
+}
+
+// maxWorkers returns the highest concurrency of the stages
+func maxWorkers(stages []Stage) int {
+	workers := 0
+	for _, stage := range stages {
+		workers = max(workers, stage.Workers)
+	}
+	return workers
+}
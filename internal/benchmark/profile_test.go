@@ -0,0 +1,64 @@
+package benchmark
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseProfile(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []Stage
+		wantErr bool
+	}{
+		{
+			spec: "2:30s,4:30s,8:1m,16:30s",
+			want: []Stage{{2, 30 * time.Second}, {4, 30 * time.Second}, {8, time.Minute}, {16, 30 * time.Second}},
+		},
+		{
+			spec: " 2:10s , 4 ",
+			want: []Stage{{2, 10 * time.Second}, {4, 0}},
+		},
+		{spec: "", wantErr: true},
+		{spec: "2,4:10s", wantErr: true},
+		{spec: "0:10s", wantErr: true},
+		{spec: "two:10s", wantErr: true},
+		{spec: "2:ten", wantErr: true},
+		{spec: "2:-1s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseProfile(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseProfile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadProfile(t *testing.T) {
+	input := `# find the knee
+2:30s
+
+4:30s
+8
+`
+	got, err := ReadProfile(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadProfile() failed: %v", err)
+	}
+
+	want := []Stage{{2, 30 * time.Second}, {4, 30 * time.Second}, {8, 0}}
+	if !slices.Equal(got, want) {
+		t.Errorf("ReadProfile() = %v, want %v", got, want)
+	}
+	if maxWorkers(got) != 8 {
+		t.Errorf("Expected 8 max workers, got %d", maxWorkers(got))
+	}
+}
@@ -0,0 +1,160 @@
+package benchmark
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sandinv/benchmark/internal/parser"
+	"github.com/sandinv/benchmark/internal/stats"
+)
+
+// progressPrecision is the number of significant digits of the rolling percentiles of the progress lines
+const progressPrecision = 2
+
+// InputProgress reports how much of the input was read, such as a parser.CountingReader
+type InputProgress interface {
+	// BytesRead returns the number of bytes read so far and the number of bytes
+	// expected to be read over the run, 0 when unknown
+	BytesRead() (read, total int64)
+}
+
+// timeBudgeted is implemented by the sources stopping after a time budget, such as parser.LoopSource
+type timeBudgeted interface {
+	Duration() time.Duration
+}
+
+// progressReporter follows the results as they are collected to report the progress of a run
+type progressReporter struct {
+	start time.Time
+	input InputProgress // nil when unknown
+	end   time.Time     // time the run is bound to end by, zero when unknown
+
+	queries int
+	errors  int
+
+	// Measured queries completed since the previous report, for the current throughput and latency
+	windowStart   time.Time
+	windowQueries int
+	window        *stats.Histogram
+}
+
+// newProgressReporter creates the progress reporter of a run started at the given time
+func newProgressReporter(start time.Time, input InputProgress, end time.Time) *progressReporter {
+	return &progressReporter{
+		start:       start,
+		input:       input,
+		end:         end,
+		windowStart: start,
+		window:      stats.NewHistogram(progressPrecision),
+	}
+}
+
+// add counts the result of a query. Only the measured queries, leaving out the warm-up and
+// EXPLAIN ANALYZE ones as the statistics do, count towards the throughput and latency.
+func (p *progressReporter) add(res result, measured bool) {
+	p.queries++
+	if res.Error != nil {
+		p.errors++
+	}
+	if !measured {
+		return
+	}
+	p.windowQueries++
+	if res.Error == nil {
+		p.window.Record(res.Duration)
+	}
+}
+
+// report returns the progress line at the given time, with the throughput and latency
+// of the queries completed since the previous report, and starts a new window
+func (p *progressReporter) report(now time.Time) string {
+	var line strings.Builder
+	_, _ = fmt.Fprintf(&line, "Progress: %d queries (%d errors), %.1f queries/s",
+		p.queries, p.errors, rate(p.windowQueries, now.Sub(p.windowStart)))
+	if p.window.Count() > 0 {
+		_, _ = fmt.Fprintf(&line, ", P50 %v, P99 %v",
+			p.window.Percentile(50).Round(time.Microsecond), p.window.Percentile(99).Round(time.Microsecond))
+	}
+	if p.input != nil {
+		read, total := p.input.BytesRead()
+		if total > 0 {
+			_, _ = fmt.Fprintf(&line, ", input %s of %s (%.1f%%)",
+				formatBytes(read), formatBytes(total), float64(read)/float64(total)*100)
+		} else {
+			_, _ = fmt.Fprintf(&line, ", input %s", formatBytes(read))
+		}
+	}
+	if eta, ok := p.eta(now); ok {
+		_, _ = fmt.Fprintf(&line, ", ETA %v", eta.Round(time.Second))
+	}
+
+	p.windowStart = now
+	p.windowQueries = 0
+	p.window = stats.NewHistogram(progressPrecision)
+	return line.String()
+}
+
+// eta estimates the time left until the end of the run from the share of the input read so far
+// and from the time the run is bound to end by, whichever comes first. It reports false when
+// neither is known.
+func (p *progressReporter) eta(now time.Time) (time.Duration, bool) {
+	var eta time.Duration
+	ok := false
+	if p.input != nil {
+		if read, total := p.input.BytesRead(); read > 0 && total > 0 {
+			left := float64(max(total-read, 0)) / float64(read)
+			eta, ok = time.Duration(float64(now.Sub(p.start))*left), true
+		}
+	}
+	if !p.end.IsZero() {
+		if left := max(p.end.Sub(now), 0); !ok || left < eta {
+			eta, ok = left, true
+		}
+	}
+	return eta, ok
+}
+
+// runEnd returns the time a run started at the given time is bound to end by, either because
+// the source has a time budget or because every stage of the load profile has a duration,
+// or zero when the run lasts until the end of the input
+func runEnd(start time.Time, source parser.Source, stages []Stage) time.Time {
+	var end time.Time
+	if budgeted, ok := source.(timeBudgeted); ok && budgeted.Duration() > 0 {
+		end = start.Add(budgeted.Duration())
+	}
+
+	var profile time.Duration
+	for _, stage := range stages {
+		if stage.Duration == 0 {
+			return end
+		}
+		profile += stage.Duration
+	}
+	if end.IsZero() || start.Add(profile).Before(end) {
+		end = start.Add(profile)
+	}
+	return end
+}
+
+// rate returns the number of events per second over the elapsed time
+func rate(events int, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(events) / elapsed.Seconds()
+}
+
+// formatBytes formats a number of bytes with a binary unit
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
@@ -0,0 +1,105 @@
+package benchmark
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sandinv/benchmark/internal/parser"
+)
+
+// fakeInput reports a fixed amount of input read
+type fakeInput struct {
+	read, total int64
+}
+
+func (f fakeInput) BytesRead() (int64, int64) {
+	return f.read, f.total
+}
+
+func TestProgressReport(t *testing.T) {
+	start := time.Now()
+	p := newProgressReporter(start, fakeInput{read: 1 << 20, total: 4 << 20}, time.Time{})
+
+	for i := 1; i <= 100; i++ {
+		p.add(result{Duration: time.Duration(i) * time.Millisecond}, true)
+	}
+	p.add(result{Error: errors.New("query failed")}, true)
+
+	line := p.report(start.Add(10 * time.Second))
+	for _, want := range []string{
+		"101 queries (1 errors)", "10.1 queries/s", "P50 50.463ms", "P99 98.839ms",
+		"input 1.0 MiB of 4.0 MiB (25.0%)", "ETA 30s",
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("Expected %q in the progress line %q", want, line)
+		}
+	}
+
+	// The throughput and the percentiles only cover the queries since the previous report
+	p.add(result{Duration: 500 * time.Millisecond}, true)
+	line = p.report(start.Add(20 * time.Second))
+	if !strings.Contains(line, "102 queries (1 errors), 0.1 queries/s, P50 500ms") {
+		t.Errorf("Unexpected progress line %q", line)
+	}
+
+	// The warm-up and EXPLAIN ANALYZE queries are counted but left out of the window
+	p.add(result{Duration: 5 * time.Second}, false)
+	line = p.report(start.Add(30 * time.Second))
+	if !strings.Contains(line, "103 queries (1 errors), 0.0 queries/s") || strings.Contains(line, "P50") {
+		t.Errorf("Expected no measured queries in the window, got %q", line)
+	}
+}
+
+func TestProgressETA(t *testing.T) {
+	start := time.Now()
+	now := start.Add(time.Minute)
+
+	tests := []struct {
+		name   string
+		input  InputProgress
+		end    time.Time
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "unknown", input: fakeInput{read: 100}},
+		{name: "no input", end: start.Add(5 * time.Minute), want: 4 * time.Minute, wantOK: true},
+		{name: "input share", input: fakeInput{read: 25, total: 100}, want: 3 * time.Minute, wantOK: true},
+		{name: "earliest end", input: fakeInput{read: 25, total: 100}, end: start.Add(2 * time.Minute), want: time.Minute, wantOK: true},
+		{name: "end passed", end: start.Add(time.Second), want: 0, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newProgressReporter(start, tt.input, tt.end)
+			got, ok := p.eta(now)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("eta() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRunEnd(t *testing.T) {
+	start := time.Now()
+	loop, err := parser.NewLoopSource(func() (parser.Source, error) {
+		return parser.NewSource(parser.FormatCSV, strings.NewReader(""), false, nil)
+	}, 0, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("NewLoopSource() failed: %v", err)
+	}
+
+	if got := runEnd(start, loop, []Stage{{Workers: 4}}); !got.Equal(start.Add(10 * time.Minute)) {
+		t.Errorf("Expected the run to end with the time budget, got %v", got.Sub(start))
+	}
+	profile := []Stage{{Workers: 2, Duration: time.Minute}, {Workers: 4, Duration: 2 * time.Minute}}
+	if got := runEnd(start, loop, profile); !got.Equal(start.Add(3 * time.Minute)) {
+		t.Errorf("Expected the run to end with the load profile, got %v", got.Sub(start))
+	}
+
+	source, _ := parser.NewSource(parser.FormatCSV, strings.NewReader(""), false, nil)
+	if got := runEnd(start, source, []Stage{{Workers: 2, Duration: time.Minute}, {Workers: 4}}); !got.IsZero() {
+		t.Errorf("Expected no end for a run lasting until the end of the input, got %v", got.Sub(start))
+	}
+}
@@ -0,0 +1,84 @@
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sandinv/benchmark/internal/database"
+	"github.com/sandinv/benchmark/internal/parser"
+	"github.com/sandinv/benchmark/internal/stats"
+)
+
+// SourceOpener opens a new source over the same input for each run of a sweep
+type SourceOpener func() (parser.Source, io.Closer, error)
+
+// ParseWorkerCounts parses a comma separated list of worker counts and ranges,
+// e.g. "1,2,4,8" or "1-4,8,16". The returned list is sorted and free of duplicates.
+func ParseWorkerCounts(spec string) ([]int, error) {
+	var counts []int
+	for field := range strings.SplitSeq(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		from, to, isRange := strings.Cut(field, "-")
+		first, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil || first <= 0 {
+			return nil, fmt.Errorf("invalid number of workers %q", field)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(strings.TrimSpace(to)); err != nil || last < first {
+				return nil, fmt.Errorf("invalid range of workers %q", field)
+			}
+		}
+
+		for n := first; n <= last; n++ {
+			counts = append(counts, n)
+		}
+	}
+
+	if len(counts) == 0 {
+		return nil, fmt.Errorf("no number of workers provided")
+	}
+
+	slices.Sort(counts)
+	return slices.Compact(counts), nil
+}
+
+// Sweep runs the benchmark over the same input once per number of workers, one run after the
+// other with the same settings, and adds the statistics of each run to the sweep.
+// It stops at the first run that fails or when the context is cancelled.
+func Sweep(ctx context.Context, db *database.Database, config Config, workerCounts []int, open SourceOpener, sweep *stats.Sweep) error {
+	for _, workers := range workerCounts {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		source, closer, err := open()
+		if err != nil {
+			return fmt.Errorf("couldn't open the input for %d workers: %w", workers, err)
+		}
+
+		config.Workers = workers
+		config.Stages = nil
+		statistics, err := NewRunner(db, config).Run(ctx, source)
+		_ = closer.Close()
+		if err != nil {
+			return fmt.Errorf("run with %d workers: %w", workers, err)
+		}
+
+		sweep.Add(workers, statistics)
+		point := sweep.Points[len(sweep.Points)-1]
+		log.Printf("Sweep: %d workers: %d queries, %.1f%% errors, %.1f queries/s, P50 %v, P99 %v",
+			workers, point.Queries, point.ErrorRate*100, point.Throughput, point.P50, point.P99)
+	}
+
+	return nil
+}
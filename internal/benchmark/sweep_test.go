@@ -0,0 +1,35 @@
+package benchmark
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseWorkerCounts(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []int
+		wantErr bool
+	}{
+		{spec: "1,2,4,8", want: []int{1, 2, 4, 8}},
+		{spec: "8, 1-3 ,2", want: []int{1, 2, 3, 8}},
+		{spec: "4-4", want: []int{4}},
+		{spec: "", wantErr: true},
+		{spec: "0,2", wantErr: true},
+		{spec: "4-2", wantErr: true},
+		{spec: "two", wantErr: true},
+		{spec: "1-x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseWorkerCounts(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWorkerCounts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseWorkerCounts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package benchmark
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Warmup is the warm-up phase of a run, whose queries are executed but recorded apart
+// from the statistics. It spans either a number of queries or a duration.
+type Warmup struct {
+	Queries  int           // first queries completed
+	Duration time.Duration // queries started within the duration from the start of the run
+}
+
+// ParseWarmup parses a warm-up given as a number of queries, e.g. "500", or a duration, e.g. "30s".
+// An empty string or "0" disables the warm-up.
+func ParseWarmup(spec string) (Warmup, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return Warmup{}, nil
+	}
+
+	if queries, err := strconv.Atoi(spec); err == nil {
+		if queries < 0 {
+			return Warmup{}, fmt.Errorf("invalid number of queries %q", spec)
+		}
+		return Warmup{Queries: queries}, nil
+	}
+
+	duration, err := time.ParseDuration(spec)
+	if err != nil || duration < 0 {
+		return Warmup{}, fmt.Errorf("invalid warm-up %q: expected a number of queries or a duration", spec)
+	}
+	return Warmup{Duration: duration}, nil
+}
+
+// warmupTracker tells the warm-up queries apart as their results are collected
+type warmupTracker struct {
+	queries int       // warm-up queries left
+	end     time.Time // end of the warm-up duration, zero without one
+}
+
+// newWarmupTracker creates the tracker of the warm-up of a run started at the given time
+func newWarmupTracker(warmup Warmup, start time.Time) *warmupTracker {
+	tracker := &warmupTracker{queries: warmup.Queries}
+	if warmup.Duration > 0 {
+		tracker.end = start.Add(warmup.Duration)
+	}
+	return tracker
+}
+
+// isWarmup reports whether the query started at the given time belongs to the warm-up.
+// It must be called once per query in completion order.
+func (w *warmupTracker) isWarmup(start time.Time) bool {
+	if w.queries > 0 {
+		w.queries--
+		return true
+	}
+	return start.Before(w.end)
+}
+
+// inProgress reports whether the query started at the given time would belong to the warm-up,
+// without counting it towards the warm-up queries
+func (w *warmupTracker) inProgress(start time.Time) bool {
+	return w.queries > 0 || start.Before(w.end)
+}
@@ -0,0 +1,100 @@
+package benchmark
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sandinv/benchmark/internal/stats"
+)
+
+func TestParseWarmup(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Warmup
+		wantErr bool
+	}{
+		{spec: "", want: Warmup{}},
+		{spec: "0", want: Warmup{}},
+		{spec: "500", want: Warmup{Queries: 500}},
+		{spec: "30s", want: Warmup{Duration: 30 * time.Second}},
+		{spec: " 1m30s ", want: Warmup{Duration: 90 * time.Second}},
+		{spec: "-5", wantErr: true},
+		{spec: "-5s", wantErr: true},
+		{spec: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseWarmup(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWarmup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseWarmup() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWarmupTracker(t *testing.T) {
+	start := time.Now()
+
+	byCount := newWarmupTracker(Warmup{Queries: 2}, start)
+	for i, want := range []bool{true, true, false, false} {
+		if got := byCount.isWarmup(start); got != want {
+			t.Errorf("Query %d: isWarmup() = %v, want %v", i, got, want)
+		}
+	}
+
+	pending := newWarmupTracker(Warmup{Queries: 1}, start)
+	if !pending.inProgress(start) || !pending.inProgress(start) {
+		t.Error("Expected the warm-up to be in progress")
+	}
+	if !pending.isWarmup(start) || pending.inProgress(start) {
+		t.Error("Expected inProgress() to leave the warm-up query to isWarmup()")
+	}
+
+	byDuration := newWarmupTracker(Warmup{Duration: time.Second}, start)
+	if !byDuration.isWarmup(start.Add(999 * time.Millisecond)) {
+		t.Error("Expected a query started within the duration to be a warm-up query")
+	}
+	if byDuration.isWarmup(start.Add(time.Second)) {
+		t.Error("Expected a query started after the duration to be measured")
+	}
+
+	none := newWarmupTracker(Warmup{}, start)
+	if none.isWarmup(start) {
+		t.Error("Expected no warm-up query without warm-up")
+	}
+}
+
+func TestCollectResultWarmupWithExplain(t *testing.T) {
+	start := time.Now()
+	statistics := stats.New()
+	statistics.SetExplainRate(0.5)
+	warmup := newWarmupTracker(Warmup{Queries: 2}, start)
+	runner := &Runner{}
+
+	// The sampled queries don't use up the 2 warm-up queries
+	for i, explained := range []bool{true, false, true, false, true, false} {
+		runner.collectResult(result{
+			Hostname:  "host_000001",
+			Start:     start,
+			End:       start.Add(time.Millisecond),
+			Duration:  time.Duration(i+1) * time.Millisecond,
+			Explained: explained,
+		}, statistics, warmup)
+	}
+	statistics.Compute()
+
+	if statistics.Warmup.Queries != 2 || statistics.Warmup.MaxTime != 4*time.Millisecond {
+		t.Errorf("Expected the 2 first measured queries in the warm-up, got %+v", statistics.Warmup)
+	}
+	if statistics.TotalQueries != 1 || statistics.MaxTime != 6*time.Millisecond {
+		t.Errorf("Expected 1 measured query, got %d", statistics.TotalQueries)
+	}
+	// The 2 queries sampled during the warm-up are dropped
+	if statistics.Explain.Client.Queries != 1 || statistics.Explain.Client.MaxTime != 5*time.Millisecond {
+		t.Errorf("Expected the last sampled query in the explain statistics, got %+v", statistics.Explain.Client)
+	}
+}
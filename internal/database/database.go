@@ -4,8 +4,11 @@
 // It handles validation of connection strings, pinging the database to ensure availability,
 // and setting up optimal connection pool settings based on worker count.
 //
-// Query execution supports context cancellation for graceful shutdown, with a 3-second
-// timeout per query. The package currently does not support SSL/TLS connections.
+// Queries are described by templates with named placeholders bound to the query parameters,
+// the built-in template being the cpu_usage query. Query execution supports context
+// cancellation for graceful shutdown, with a configurable timeout per query (3 seconds by
+// default) that may also be enforced by the server with statement_timeout.
+// The package currently does not support SSL/TLS connections.
 package database
 
 import (
@@ -17,20 +20,44 @@ import (
 	pq "github.com/lib/pq"
 )
 
+// DefaultQueryTimeout is the default timeout of each query
+const DefaultQueryTimeout = 3 * time.Second
+
+// Options configures the queries run over a connection
+type Options struct {
+	// QueryTimeout is the time after which a query is cancelled by the client, DefaultQueryTimeout when 0
+	QueryTimeout time.Duration
+	// StatementTimeout is set as the statement_timeout of every session so that the server
+	// aborts the statements running longer, 0 keeps the server setting
+	StatementTimeout time.Duration
+	// Retry is the retry policy of the queries failing with a transient error
+	Retry RetryPolicy
+	// Checksum computes a checksum of the rows returned by every query to verify the results
+	Checksum bool
+}
+
 type Database struct {
-	db *sql.DB
+	db           *sql.DB
+	queryTimeout time.Duration
+	retry        RetryPolicy
+	checksum     bool
 }
 
 // Connect establishes a connection to the database using connection string provided and verifies that it is connected
-func Connect(connectionString string) (*Database, error) {
+func Connect(connectionString string, options Options) (*Database, error) {
 
 	// Validate if the connection string is valid
-	_, err := pq.ParseURL(connectionString)
+	dsn, err := pq.ParseURL(connectionString)
 	if err != nil {
 		return nil, fmt.Errorf("invalid database connection string: %w", err)
 	}
 
-	db, err := sql.Open("postgres", connectionString)
+	// Parameters unknown to the driver are set on every session it opens
+	if options.StatementTimeout > 0 {
+		dsn += fmt.Sprintf(" statement_timeout=%d", options.StatementTimeout.Milliseconds())
+	}
+
+	db, err := sql.Open("postgres", dsn)
 
 	if err != nil {
 		return nil, err
@@ -44,19 +71,29 @@ func Connect(connectionString string) (*Database, error) {
 		return nil, err
 	}
 
-	return &Database{db}, nil
+	queryTimeout := options.QueryTimeout
+	if queryTimeout <= 0 {
+		queryTimeout = DefaultQueryTimeout
+	}
+
+	return &Database{db: db, queryTimeout: queryTimeout, retry: options.Retry, checksum: options.Checksum}, nil
+}
+
+// MaxAttempts returns the number of attempts of each query allowed by the retry policy
+func (d *Database) MaxAttempts() int {
+	return max(d.retry.MaxAttempts, 1)
 }
 
-// ConfigurePool sets up the connection pool for optimal performance
-func (d *Database) ConfigurePool(workers int) {
+// ConfigurePool sets up the connection pool for the given number of concurrent queries
+func (d *Database) ConfigurePool(connections int) {
 
-	maxOpenConns := workers
-	if workers < 5 {
+	maxOpenConns := connections
+	if connections < 5 {
 		maxOpenConns = maxOpenConns * 2
 	}
 
-	// Max connection would equal to workers * 2 if the number of workers < 5
+	// Max connection would equal to connections * 2 if the number of connections < 5
 	d.db.SetMaxOpenConns(maxOpenConns)
-	d.db.SetMaxIdleConns(workers)
+	d.db.SetMaxIdleConns(connections)
 	d.db.SetConnMaxLifetime(5 * time.Minute)
 }
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	pq "github.com/lib/pq"
+)
+
+// Classes of the query errors returned by ErrorClass. Errors reported by the
+// server are classified by their SQLSTATE code instead, e.g. "sqlstate_57014".
+const (
+	ErrorClassDeadline = "deadline_exceeded" // the query context deadline expired
+	ErrorClassCanceled = "canceled"          // the query context was canceled, e.g. on shutdown
+	ErrorClassNetwork  = "network"           // the connection to the server failed
+	ErrorClassOther    = "other"
+)
+
+// ErrorClass returns the class of a query error
+func ErrorClass(err error) string {
+	var pqErr *pq.Error
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassDeadline
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.As(err, &pqErr):
+		return "sqlstate_" + string(pqErr.Code)
+	case errors.As(err, &netErr), errors.Is(err, driver.ErrBadConn), errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorClassNetwork
+	default:
+		return ErrorClassOther
+	}
+}
+
+// IsTimeout reports whether a query error is a timeout, either the query timeout of the
+// client or the statement_timeout of the server
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	// The query_canceled code is shared with the other cancellations of the statement
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "57014" && strings.Contains(pqErr.Message, "statement timeout")
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	pq "github.com/lib/pq"
+)
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "deadline", err: fmt.Errorf("query: %w", context.DeadlineExceeded), want: ErrorClassDeadline},
+		{name: "canceled", err: context.Canceled, want: ErrorClassCanceled},
+		{name: "sql error", err: &pq.Error{Code: "42P01", Message: "relation \"cpu_usage\" does not exist"}, want: "sqlstate_42P01"},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, want: ErrorClassNetwork},
+		{name: "bad connection", err: driver.ErrBadConn, want: ErrorClassNetwork},
+		{name: "other", err: errors.New("missing value for parameter limit"), want: ErrorClassOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorClass(tt.err); got != tt.want {
+				t.Errorf("ErrorClass() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "query timeout", err: fmt.Errorf("%w: %w", context.DeadlineExceeded, &pq.Error{Code: "57014", Message: "canceling statement due to user request"}), want: true},
+		{name: "statement timeout", err: &pq.Error{Code: "57014", Message: "canceling statement due to statement timeout"}, want: true},
+		{name: "cancel request", err: &pq.Error{Code: "57014", Message: "canceling statement due to user request"}},
+		{name: "canceled", err: context.Canceled},
+		{name: "sql error", err: &pq.Error{Code: "42P01", Message: "relation \"cpu_usage\" does not exist"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTimeout(tt.err); got != tt.want {
+				t.Errorf("IsTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Plan holds the server-side statistics of a query run with EXPLAIN ANALYZE
+type Plan struct {
+	PlanningTime     time.Duration
+	ExecutionTime    time.Duration
+	Chunks           int   // hypertable chunks scanned
+	SharedHitBlocks  int64 // blocks found in the shared buffers
+	SharedReadBlocks int64 // blocks read from disk or the OS cache
+}
+
+// explainNode is a node of the JSON plan output by EXPLAIN
+type explainNode struct {
+	RelationName     string        `json:"Relation Name"`
+	ActualLoops      float64       `json:"Actual Loops"`
+	SharedHitBlocks  int64         `json:"Shared Hit Blocks"`
+	SharedReadBlocks int64         `json:"Shared Read Blocks"`
+	Plans            []explainNode `json:"Plans"`
+}
+
+// Explain runs the query template with EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON), which executes
+// the query on the server without returning its rows, and returns the statistics of its plan
+func (d *Database) Explain(ctx context.Context, template Template, params QueryParams) (plan Plan, err error) {
+	args, err := template.bind(params)
+	if err != nil {
+		return plan, err
+	}
+
+	// Create a timeout context that respects the parent context cancellation
+	ctx, cancel := context.WithTimeout(ctx, d.queryTimeout)
+	defer cancel()
+
+	var output []byte
+	if err := d.db.QueryRowContext(ctx, "EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) "+template.SQL, args...).Scan(&output); err != nil {
+		if ctx.Err() != nil && !errors.Is(err, ctx.Err()) {
+			err = fmt.Errorf("%w: %w", ctx.Err(), err)
+		}
+		return plan, err
+	}
+	return parsePlan(output)
+}
+
+// parsePlan extracts the statistics of a plan output by EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON)
+func parsePlan(output []byte) (Plan, error) {
+	var explain []struct {
+		Plan          explainNode `json:"Plan"`
+		PlanningTime  float64     `json:"Planning Time"`  // milliseconds
+		ExecutionTime float64     `json:"Execution Time"` // milliseconds
+	}
+	if err := json.Unmarshal(output, &explain); err != nil {
+		return Plan{}, fmt.Errorf("invalid EXPLAIN output: %w", err)
+	}
+	if len(explain) != 1 {
+		return Plan{}, fmt.Errorf("invalid EXPLAIN output: expected 1 plan, got %d", len(explain))
+	}
+
+	// Buffer counts of a node include the ones of its children
+	root := explain[0]
+	chunks := make(map[string]bool)
+	countChunks(root.Plan, chunks)
+	return Plan{
+		PlanningTime:     time.Duration(root.PlanningTime * float64(time.Millisecond)),
+		ExecutionTime:    time.Duration(root.ExecutionTime * float64(time.Millisecond)),
+		Chunks:           len(chunks),
+		SharedHitBlocks:  root.Plan.SharedHitBlocks,
+		SharedReadBlocks: root.Plan.SharedReadBlocks,
+	}, nil
+}
+
+// countChunks adds the hypertable chunks scanned by the node and its children to the set.
+// Chunks are the _hyper_<hypertable>_<id>_chunk relations, or compress_hyper_ ones once compressed,
+// and those pruned at run time are never executed.
+func countChunks(node explainNode, chunks map[string]bool) {
+	if strings.Contains(node.RelationName, "hyper_") && strings.HasSuffix(node.RelationName, "_chunk") && node.ActualLoops > 0 {
+		chunks[node.RelationName] = true
+	}
+	for _, child := range node.Plans {
+		countChunks(child, chunks)
+	}
+}
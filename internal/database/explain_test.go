@@ -0,0 +1,80 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+const explainOutput = `[
+  {
+    "Plan": {
+      "Node Type": "Custom Scan",
+      "Custom Plan Provider": "ChunkAppend",
+      "Actual Loops": 1,
+      "Shared Hit Blocks": 120,
+      "Shared Read Blocks": 8,
+      "Plans": [
+        {
+          "Node Type": "Index Scan",
+          "Relation Name": "_hyper_1_3_chunk",
+          "Actual Loops": 1,
+          "Shared Hit Blocks": 60,
+          "Shared Read Blocks": 8
+        },
+        {
+          "Node Type": "Index Scan",
+          "Relation Name": "_hyper_1_4_chunk",
+          "Actual Loops": 1,
+          "Shared Hit Blocks": 60,
+          "Shared Read Blocks": 0,
+          "Plans": [
+            {
+              "Node Type": "Seq Scan",
+              "Relation Name": "compress_hyper_2_7_chunk",
+              "Actual Loops": 1
+            }
+          ]
+        },
+        {
+          "Node Type": "Index Scan",
+          "Relation Name": "_hyper_1_5_chunk",
+          "Actual Loops": 0
+        },
+        {
+          "Node Type": "Seq Scan",
+          "Relation Name": "hosts",
+          "Actual Loops": 1
+        }
+      ]
+    },
+    "Planning Time": 0.25,
+    "Triggers": [],
+    "Execution Time": 12.5
+  }
+]`
+
+func TestParsePlan(t *testing.T) {
+	plan, err := parsePlan([]byte(explainOutput))
+	if err != nil {
+		t.Fatalf("parsePlan() failed: %v", err)
+	}
+
+	want := Plan{
+		PlanningTime:     250 * time.Microsecond,
+		ExecutionTime:    12500 * time.Microsecond,
+		Chunks:           3,
+		SharedHitBlocks:  120,
+		SharedReadBlocks: 8,
+	}
+	if plan != want {
+		t.Errorf("parsePlan() = %+v, want %+v", plan, want)
+	}
+}
+
+func TestParsePlanInvalid(t *testing.T) {
+	for _, output := range []string{"", "{}", "[]", `[{"Plan": {}}, {"Plan": {}}]`} {
+		if _, err := parsePlan([]byte(output)); err == nil {
+			t.Errorf("Expected an error for %q", output)
+		}
+	}
+}
@@ -2,10 +2,16 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/fnv"
 	"time"
 )
 
-const queryTimeout = 3 * time.Second
+// query is the SQL of DefaultTemplate
 const query = `
     SELECT 
         time_bucket('1 minute', ts) AS bucket,
@@ -16,22 +22,133 @@ const query = `
      GROUP BY bucket
      ORDER BY bucket`
 
-// QueryParams represents parameters for a CPU usage query
+// referenceQuery is the SQL of DefaultReferenceTemplate, computing the buckets with
+// date_trunc instead of time_bucket
+const referenceQuery = `
+    SELECT
+        date_trunc('minute', ts) AS bucket,
+        MAX(usage) AS max_usage,
+        MIN(usage) AS min_usage
+     FROM cpu_usage
+     WHERE host = $1 AND ts >= $2 AND ts <= $3
+     GROUP BY 1
+     ORDER BY 1`
+
+// QueryParams represents parameters for a query
 type QueryParams struct {
 	Hostname  string
 	StartTime time.Time
 	EndTime   time.Time
+	// IssueTime is the time the query was originally issued, used to replay the input
+	// with its own timing. It is zero when the input does not record it.
+	IssueTime time.Time
+	// Values holds any additional parameter by name, e.g. extra input columns
+	Values map[string]any
+}
+
+// Value returns the value of the parameter with the given name, as referenced by query templates.
+// The dedicated fields are missing when unset, i.e. when the input has no such column, so that
+// the templates binding them fail instead of querying an empty hostname or the zero time.
+func (p QueryParams) Value(name string) (any, bool) {
+	if value, ok := p.Values[name]; ok {
+		return value, true
+	}
+
+	switch name {
+	case "hostname":
+		return p.Hostname, p.Hostname != ""
+	case "start_time":
+		return p.StartTime, !p.StartTime.IsZero()
+	case "end_time":
+		return p.EndTime, !p.EndTime.IsZero()
+	case "issue_time":
+		return p.IssueTime, !p.IssueTime.IsZero()
+	}
+	return nil, false
+}
+
+// Result describes the outcome of a query execution
+type Result struct {
+	Rows  int64 // number of rows returned
+	Bytes int64 // approximate size of the rows returned, as the sum of the sizes of their values
+	// Checksum is a hash of the values of the rows returned, in order, when the database
+	// computes checksums, 0 otherwise
+	Checksum uint64
+	// FirstRow is the time from the call to the first row, or to the end of the result when it
+	// is empty, and Total the time of the whole call. Both include the failed attempts and
+	// their backoff when the query is retried, so Total - FirstRow is the time spent fetching
+	// the rows after the first one.
+	FirstRow time.Duration
+	Total    time.Duration
+	// Attempts is the number of times the query was run, more than 1 when it was retried
+	Attempts int
+	// FirstAttempt is the duration of the first attempt, without the retries and their backoff
+	FirstAttempt time.Duration
+}
+
+// Execute runs the query template with the given parameters, retrying it according to
+// the retry policy of the database when it fails with a transient error
+func (d *Database) Execute(ctx context.Context, template Template, params QueryParams) (Result, error) {
+	args, err := template.bind(params)
+	if err != nil {
+		return Result{}, err
+	}
+
+	start := time.Now()
+	var firstAttempt time.Duration
+	for attempt := 1; ; attempt++ {
+		attemptStart := time.Now()
+		fetched, err := d.execute(ctx, template.SQL, args)
+		end := time.Now()
+		if attempt == 1 {
+			firstAttempt = end.Sub(attemptStart)
+		}
+		result := Result{
+			Rows:         fetched.rows,
+			Bytes:        fetched.bytes,
+			Checksum:     fetched.checksum,
+			Total:        end.Sub(start),
+			Attempts:     attempt,
+			FirstAttempt: firstAttempt,
+		}
+		if !fetched.firstRow.IsZero() {
+			result.FirstRow = fetched.firstRow.Sub(start)
+		}
+		if err == nil || !d.retry.shouldRetry(err, attempt) {
+			return result, err
+		}
+
+		if sleepErr := sleep(ctx, d.retry.backoff(attempt)); sleepErr != nil {
+			return result, fmt.Errorf("%w: %w", sleepErr, err)
+		}
+	}
+}
+
+// fetched describes the rows returned by a single run of a query
+type fetched struct {
+	rows     int64
+	bytes    int64
+	checksum uint64
+	firstRow time.Time // time the first row, or the end of an empty result, was received
 }
 
-// Execute runs a query with the given parameters
-func (d *Database) Execute(ctx context.Context, params QueryParams) (err error) {
+// execute runs the query once and returns the rows it returned
+func (d *Database) execute(ctx context.Context, query string, args []any) (result fetched, err error) {
 	// Create a timeout context that respects the parent context cancellation
-	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, d.queryTimeout)
 	defer cancel()
 
-	rows, err := d.db.QueryContext(ctx, query, params.Hostname, params.StartTime, params.EndTime)
+	// A query interrupted by its context may fail with a driver or server error,
+	// such as the cancellation of the statement, that is reported as the context error
+	defer func() {
+		if err != nil && ctx.Err() != nil && !errors.Is(err, ctx.Err()) {
+			err = fmt.Errorf("%w: %w", ctx.Err(), err)
+		}
+	}()
+
+	rows, err := d.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return err
+		return result, err
 	}
 	defer func() {
 		closeErr := rows.Close()
@@ -40,18 +157,58 @@ func (d *Database) Execute(ctx context.Context, params QueryParams) (err error)
 		}
 	}()
 
-	// Consume all rows - each row represents one minute with max/min CPU usage
+	columns, err := rows.Columns()
+	if err != nil {
+		return result, err
+	}
+
+	// Consume all rows as raw bytes since templates may return any columns
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	var checksum hash.Hash64
+	if d.checksum {
+		checksum = fnv.New64a()
+	}
 	for rows.Next() {
-		var (
-			bucket   time.Time
-			maxUsage float64
-			minUsage float64
-		)
-		if err := rows.Scan(&bucket, &maxUsage, &minUsage); err != nil {
-			return err
+		if result.firstRow.IsZero() {
+			result.firstRow = time.Now()
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return result, err
 		}
 		// Data is not stored since we are only interested in the benchmark of the queries
+		result.rows++
+		for _, value := range values {
+			result.bytes += int64(len(value))
+		}
+		if checksum != nil {
+			writeRow(checksum, values)
+		}
+	}
+	if checksum != nil {
+		result.checksum = checksum.Sum64()
 	}
+	if result.firstRow.IsZero() {
+		result.firstRow = time.Now()
+	}
+
+	return result, rows.Err()
+}
 
-	return rows.Err()
+// writeRow adds the values of a row to the checksum, each one prefixed with its length
+// so that the boundaries between values and rows are part of the checksum
+func writeRow(checksum hash.Hash64, values []sql.RawBytes) {
+	var prefix [binary.MaxVarintLen64]byte
+	for _, value := range values {
+		// NULL is told apart from an empty value
+		length := int64(len(value))
+		if value == nil {
+			length = -1
+		}
+		_, _ = checksum.Write(prefix[:binary.PutVarint(prefix[:], length)])
+		_, _ = checksum.Write(value)
+	}
 }
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"database/sql"
+	"hash/fnv"
+	"testing"
+)
+
+func TestWriteRow(t *testing.T) {
+	checksum := func(rows ...[]sql.RawBytes) uint64 {
+		h := fnv.New64a()
+		for _, row := range rows {
+			writeRow(h, row)
+		}
+		return h.Sum64()
+	}
+
+	row := []sql.RawBytes{sql.RawBytes("2017-01-01 08:59:00+00"), sql.RawBytes("90.5"), sql.RawBytes("12.25")}
+	if checksum(row) != checksum(row) {
+		t.Error("Expected the same rows to have the same checksum")
+	}
+
+	different := map[string][2][][]sql.RawBytes{
+		"value":    {{row}, {{row[0], sql.RawBytes("90.6"), row[2]}}},
+		"boundary": {{{sql.RawBytes("ab"), sql.RawBytes("c")}}, {{sql.RawBytes("a"), sql.RawBytes("bc")}}},
+		"null":     {{{nil}}, {{sql.RawBytes{}}}},
+		"order":    {{row, {row[0]}}, {{row[0]}, row}},
+	}
+	for name, rows := range different {
+		t.Run(name, func(t *testing.T) {
+			if checksum(rows[0]...) == checksum(rows[1]...) {
+				t.Errorf("Expected different checksums for %q and %q", rows[0], rows[1])
+			}
+		})
+	}
+}
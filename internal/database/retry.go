@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Defaults of the retry policy
+const (
+	DefaultRetryBackoff    = 100 * time.Millisecond
+	DefaultRetryMaxBackoff = 2 * time.Second
+)
+
+// DefaultRetryableClasses are the error classes retried by default: connection failures,
+// serialization failures, deadlocks, too many connections and server restarts
+var DefaultRetryableClasses = []string{
+	ErrorClassNetwork, "sqlstate_40001", "sqlstate_40P01", "sqlstate_53300", "sqlstate_57P01", "sqlstate_57P03",
+}
+
+// RetryPolicy describes how the queries failing with a transient error are retried.
+// The backoff before each retry doubles from Backoff up to MaxBackoff, and a random
+// jitter of up to half of it spreads the retries of concurrent queries.
+type RetryPolicy struct {
+	MaxAttempts int           // attempts per query including the first one, 1 or less disables the retries
+	Backoff     time.Duration // backoff before the first retry, DefaultRetryBackoff when 0
+	MaxBackoff  time.Duration // upper bound of the backoff, DefaultRetryMaxBackoff when 0
+	Classes     []string      // error classes retried, as returned by ErrorClass, DefaultRetryableClasses when empty
+}
+
+// ParseErrorClasses parses a comma separated list of error classes, e.g. "network,sqlstate_40001"
+func ParseErrorClasses(spec string) ([]string, error) {
+	var classes []string
+	for class := range strings.SplitSeq(spec, ",") {
+		class = strings.TrimSpace(class)
+		switch {
+		case class == ErrorClassDeadline || class == ErrorClassCanceled || class == ErrorClassNetwork || class == ErrorClassOther:
+		case strings.HasPrefix(class, "sqlstate_") && len(class) == len("sqlstate_")+5:
+		default:
+			return nil, fmt.Errorf("invalid error class %q", class)
+		}
+		classes = append(classes, class)
+	}
+	return classes, nil
+}
+
+// shouldRetry reports whether a query that failed with the error at the given attempt is retried
+func (p RetryPolicy) shouldRetry(err error, attempt int) bool {
+	if attempt >= p.MaxAttempts {
+		return false
+	}
+	classes := p.Classes
+	if len(classes) == 0 {
+		classes = DefaultRetryableClasses
+	}
+	return slices.Contains(classes, ErrorClass(err))
+}
+
+// backoff returns the time to wait before retrying a query that failed at the given attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff, maxBackoff := p.Backoff, p.MaxBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultRetryMaxBackoff
+	}
+	for i := 1; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, maxBackoff)
+
+	// Half of the backoff is fixed and the other half random
+	half := backoff / 2
+	return half + rand.N(backoff-half+1)
+}
+
+// sleep waits for the duration unless the context is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
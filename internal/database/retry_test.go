@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	pq "github.com/lib/pq"
+)
+
+func TestParseErrorClasses(t *testing.T) {
+	got, err := ParseErrorClasses(" network, sqlstate_40001,deadline_exceeded")
+	if err != nil {
+		t.Fatalf("ParseErrorClasses() failed: %v", err)
+	}
+	if want := []string{"network", "sqlstate_40001", "deadline_exceeded"}; !slices.Equal(got, want) {
+		t.Errorf("ParseErrorClasses() = %v, want %v", got, want)
+	}
+
+	for _, spec := range []string{"", "network,", "timeout", "sqlstate_400"} {
+		if _, err := ParseErrorClasses(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	deadlock := &pq.Error{Code: "40P01", Message: "deadlock detected"}
+	missing := &pq.Error{Code: "42P01", Message: "relation \"cpu_usage\" does not exist"}
+
+	policy := RetryPolicy{MaxAttempts: 3}
+	if !policy.shouldRetry(deadlock, 1) || !policy.shouldRetry(deadlock, 2) {
+		t.Error("Expected a deadlock to be retried up to the maximum attempts")
+	}
+	if policy.shouldRetry(deadlock, 3) {
+		t.Error("Expected no retry after the maximum attempts")
+	}
+	if policy.shouldRetry(missing, 1) || policy.shouldRetry(context.DeadlineExceeded, 1) {
+		t.Error("Expected errors outside the default classes not to be retried")
+	}
+
+	policy.Classes = []string{ErrorClassDeadline}
+	if !policy.shouldRetry(context.DeadlineExceeded, 1) || policy.shouldRetry(deadlock, 1) {
+		t.Error("Expected only the configured classes to be retried")
+	}
+
+	if (RetryPolicy{}).shouldRetry(deadlock, 1) {
+		t.Error("Expected no retry without a retry policy")
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	tests := []struct {
+		attempt int
+		want    time.Duration // upper bound, the lower bound being half of it
+	}{
+		{attempt: 1, want: 100 * time.Millisecond},
+		{attempt: 2, want: 200 * time.Millisecond},
+		{attempt: 3, want: 400 * time.Millisecond},
+		{attempt: 5, want: time.Second},
+		{attempt: 50, want: time.Second},
+	}
+
+	for _, tt := range tests {
+		for range 100 {
+			if got := policy.backoff(tt.attempt); got < tt.want/2 || got > tt.want {
+				t.Fatalf("Expected the backoff of attempt %d between %v and %v, got %v", tt.attempt, tt.want/2, tt.want, got)
+			}
+		}
+	}
+}
+
+func TestSleepCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := sleep(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the sleep to be canceled, got %v", err)
+	}
+}
@@ -0,0 +1,326 @@
+package database
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Template is a named SQL query whose parameters are bound by name to the query parameters.
+//
+// Templates are written with named placeholders such as :hostname, which are rewritten
+// to positional placeholders ($1, $2, ...) when the template is parsed. Casts (::type),
+// string literals, quoted identifiers, dollar-quoted strings, line and block comments and
+// array slices (arr[lower:upper]) are left untouched, so placeholders can't be used in
+// array subscripts.
+type Template struct {
+	Name string
+	SQL  string   // query with positional placeholders
+	Args []string // name of the parameter bound to each positional placeholder
+}
+
+// DefaultTemplate is the built-in query retrieving the max and min CPU usage per minute
+var DefaultTemplate = Template{
+	Name: "cpu_usage",
+	SQL:  query,
+	Args: []string{"hostname", "start_time", "end_time"},
+}
+
+// DefaultReferenceTemplate is a naive query returning the same rows as DefaultTemplate,
+// used as the reference to verify its results
+var DefaultReferenceTemplate = Template{
+	Name: "cpu_usage",
+	SQL:  referenceQuery,
+	Args: []string{"hostname", "start_time", "end_time"},
+}
+
+// templateNamePrefix starts the line naming each template of a query file
+const templateNamePrefix = "-- name:"
+
+// ParseTemplate creates a template from a query written with named placeholders
+func ParseTemplate(name, sql string) (Template, error) {
+	sql = strings.TrimSuffix(strings.TrimSpace(sql), ";")
+	if sql == "" {
+		return Template{}, fmt.Errorf("query %q is empty", name)
+	}
+
+	var (
+		out       strings.Builder
+		args      []string
+		positions = make(map[string]int)
+		// subscripts holds whether each open square bracket is an array subscript,
+		// as opposed to an ARRAY[...] constructor
+		subscripts []bool
+	)
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"':
+			// String literal or quoted identifier, copied up to the closing quote
+			end := strings.IndexByte(sql[i+1:], c)
+			if end < 0 {
+				return Template{}, fmt.Errorf("query %q: unterminated quote at offset %d", name, i)
+			}
+			out.WriteString(sql[i : i+end+2])
+			i += end + 2
+		case strings.HasPrefix(sql[i:], "--"):
+			// Comment, copied up to the end of the line
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			out.WriteString(sql[i : i+end])
+			i += end
+		case strings.HasPrefix(sql[i:], "/*"):
+			// Block comment, which may be nested, copied up to its end
+			end := blockCommentEnd(sql, i)
+			if end < 0 {
+				return Template{}, fmt.Errorf("query %q: unterminated comment at offset %d", name, i)
+			}
+			out.WriteString(sql[i:end])
+			i = end
+		case c == '$' && (i == 0 || !isIdentifierPart(sql[i-1])) && dollarTag(sql[i:]) != "":
+			// Dollar-quoted string, copied up to the closing tag
+			tag := dollarTag(sql[i:])
+			end := strings.Index(sql[i+len(tag):], tag)
+			if end < 0 {
+				return Template{}, fmt.Errorf("query %q: unterminated dollar quote at offset %d", name, i)
+			}
+			end += i + 2*len(tag)
+			out.WriteString(sql[i:end])
+			i = end
+		case c == '[':
+			subscripts = append(subscripts, !strings.EqualFold(previousWord(sql[:i]), "array"))
+			out.WriteByte(c)
+			i++
+		case c == ']':
+			if len(subscripts) > 0 {
+				subscripts = subscripts[:len(subscripts)-1]
+			}
+			out.WriteByte(c)
+			i++
+		case strings.HasPrefix(sql[i:], "::"):
+			// Type cast
+			out.WriteString("::")
+			i += 2
+		case c == ':' && len(subscripts) > 0 && subscripts[len(subscripts)-1]:
+			// Array slice
+			out.WriteByte(c)
+			i++
+		case c == ':' && i+1 < len(sql) && isIdentifierStart(sql[i+1]):
+			end := i + 1
+			for end < len(sql) && isIdentifierPart(sql[end]) {
+				end++
+			}
+			arg := sql[i+1 : end]
+			position, ok := positions[arg]
+			if !ok {
+				args = append(args, arg)
+				position = len(args)
+				positions[arg] = position
+			}
+			out.WriteString("$" + strconv.Itoa(position))
+			i = end
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+
+	return Template{Name: name, SQL: out.String(), Args: args}, nil
+}
+
+// ParseTemplates reads one or more query templates.
+//
+// Each template starts with a line naming it, followed by the query:
+//
+//	-- name: max_min_per_minute
+//	SELECT time_bucket('1 minute', ts) AS bucket, MAX(usage), MIN(usage)
+//	FROM cpu_usage
+//	WHERE host = :hostname AND ts >= :start_time AND ts <= :end_time
+//	GROUP BY bucket;
+//
+// A file without any name line holds a single template named "query". Otherwise only blank
+// lines and comments may come before the first name line.
+func ParseTemplates(input io.Reader) ([]Template, error) {
+	type rawTemplate struct {
+		name string
+		sql  strings.Builder
+	}
+
+	var (
+		templates []*rawTemplate
+		preamble  strings.Builder
+	)
+
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(strings.TrimSpace(line), templateNamePrefix); ok {
+			templates = append(templates, &rawTemplate{name: strings.TrimSpace(name)})
+			continue
+		}
+
+		if len(templates) == 0 {
+			preamble.WriteString(line + "\n")
+			continue
+		}
+		templates[len(templates)-1].sql.WriteString(line + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(templates) > 0 && !commentsOnly(preamble.String()) {
+		return nil, fmt.Errorf("query before the first %q line", templateNamePrefix)
+	}
+	if len(templates) == 0 {
+		template, err := ParseTemplate("query", preamble.String())
+		if err != nil {
+			return nil, err
+		}
+		return []Template{template}, nil
+	}
+
+	result := make([]Template, 0, len(templates))
+	seen := make(map[string]bool)
+	for _, raw := range templates {
+		if raw.name == "" {
+			return nil, fmt.Errorf("query without name")
+		}
+		if seen[raw.name] {
+			return nil, fmt.Errorf("duplicate query name %q", raw.name)
+		}
+		seen[raw.name] = true
+
+		template, err := ParseTemplate(raw.name, raw.sql.String())
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, template)
+	}
+
+	return result, nil
+}
+
+// bind returns the query arguments of the template for the given parameters
+func (t Template) bind(params QueryParams) ([]any, error) {
+	args := make([]any, len(t.Args))
+	for i, name := range t.Args {
+		value, ok := params.Value(name)
+		if !ok {
+			return nil, fmt.Errorf("query %q: missing parameter %q", t.Name, name)
+		}
+		args[i] = value
+	}
+	return args, nil
+}
+
+// Key returns the parameters the template binds from the query parameters as a string,
+// such as "hostname=host_000001 start_time=2017-01-01T08:59:22Z", which identifies the input
+// record a result belongs to. Times are formatted in RFC 3339 format in UTC.
+func (t Template) Key(params QueryParams) (string, error) {
+	args, err := t.bind(params)
+	if err != nil {
+		return "", err
+	}
+
+	var key strings.Builder
+	for i, name := range t.Args {
+		if i > 0 {
+			key.WriteByte(' ')
+		}
+		value := args[i]
+		if ts, ok := value.(time.Time); ok {
+			value = ts.UTC().Format(time.RFC3339Nano)
+		}
+		_, _ = fmt.Fprintf(&key, "%s=%v", name, value)
+	}
+	return key.String(), nil
+}
+
+// commentsOnly reports whether the SQL holds nothing but whitespace and comments
+func commentsOnly(sql string) bool {
+	for i := 0; i < len(sql); {
+		switch {
+		case strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				return true
+			}
+			i += end
+		case strings.HasPrefix(sql[i:], "/*"):
+			end := blockCommentEnd(sql, i)
+			if end < 0 {
+				return false
+			}
+			i = end
+		case strings.ContainsRune(" \t\r\n", rune(sql[i])):
+			i++
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// blockCommentEnd returns the offset following the end of the block comment starting at
+// the offset, -1 when it is unterminated. Block comments nest as in PostgreSQL.
+func blockCommentEnd(sql string, start int) int {
+	depth := 0
+	for i := start; i+1 < len(sql); {
+		switch sql[i : i+2] {
+		case "/*":
+			depth++
+			i += 2
+		case "*/":
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
+// dollarTag returns the tag, such as $$ or $body$, opening the dollar-quoted string the
+// SQL starts with, or an empty string when it doesn't start with one, as for $1
+func dollarTag(sql string) string {
+	if len(sql) < 2 || sql[0] != '$' {
+		return ""
+	}
+	for i := 1; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case c == '$':
+			return sql[:i+1]
+		case i == 1 && !isIdentifierStart(c), !isIdentifierPart(c):
+			return ""
+		}
+	}
+	return ""
+}
+
+// previousWord returns the identifier the SQL ends with, ignoring trailing spaces
+func previousWord(sql string) string {
+	sql = strings.TrimRight(sql, " \t\r\n")
+	start := len(sql)
+	for start > 0 && isIdentifierPart(sql[start-1]) {
+		start--
+	}
+	return sql[start:]
+}
+
+func isIdentifierStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentifierPart(c byte) bool {
+	return isIdentifierStart(c) || (c >= '0' && c <= '9')
+}
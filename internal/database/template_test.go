@@ -0,0 +1,192 @@
+package database
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		wantSQL  string
+		wantArgs []string
+		wantErr  bool
+	}{
+		{
+			name:     "named placeholders",
+			sql:      "SELECT * FROM cpu_usage WHERE host = :hostname AND ts >= :start_time AND ts <= :end_time",
+			wantSQL:  "SELECT * FROM cpu_usage WHERE host = $1 AND ts >= $2 AND ts <= $3",
+			wantArgs: []string{"hostname", "start_time", "end_time"},
+		},
+		{
+			name:     "repeated placeholder",
+			sql:      "SELECT :hostname, :start_time WHERE host = :hostname",
+			wantSQL:  "SELECT $1, $2 WHERE host = $1",
+			wantArgs: []string{"hostname", "start_time"},
+		},
+		{
+			name:     "casts, literals and comments",
+			sql:      "SELECT ts::date, ':literal', \"col:name\" -- :comment\nFROM t WHERE host = :hostname;",
+			wantSQL:  "SELECT ts::date, ':literal', \"col:name\" -- :comment\nFROM t WHERE host = $1",
+			wantArgs: []string{"hostname"},
+		},
+		{
+			name:     "block comments",
+			sql:      "SELECT /* :outer /* :nested */ :still_comment */ 1 WHERE host = :hostname",
+			wantSQL:  "SELECT /* :outer /* :nested */ :still_comment */ 1 WHERE host = $1",
+			wantArgs: []string{"hostname"},
+		},
+		{
+			name:     "dollar quotes",
+			sql:      "SELECT $$:quoted$$, $body$ :quoted $$ $body$ WHERE host = :hostname",
+			wantSQL:  "SELECT $$:quoted$$, $body$ :quoted $$ $body$ WHERE host = $1",
+			wantArgs: []string{"hostname"},
+		},
+		{
+			name:     "array slices",
+			sql:      "SELECT arr[lower:upper], arr[1:2], (arr)[a:b][c:d] WHERE host = ANY(ARRAY[:hostname])",
+			wantSQL:  "SELECT arr[lower:upper], arr[1:2], (arr)[a:b][c:d] WHERE host = ANY(ARRAY[$1])",
+			wantArgs: []string{"hostname"},
+		},
+		{
+			name:    "unterminated comment",
+			sql:     "SELECT 1 /* :oops",
+			wantErr: true,
+		},
+		{
+			name:    "unterminated dollar quote",
+			sql:     "SELECT $tag$ :oops $$",
+			wantErr: true,
+		},
+		{
+			name:     "no placeholder",
+			sql:      "SELECT 1",
+			wantSQL:  "SELECT 1",
+			wantArgs: nil,
+		},
+		{
+			name:    "empty query",
+			sql:     "  ; ",
+			wantErr: true,
+		},
+		{
+			name:    "unterminated literal",
+			sql:     "SELECT 'oops",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := ParseTemplate("test", tt.sql)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if template.SQL != tt.wantSQL {
+				t.Errorf("Expected SQL %q, got %q", tt.wantSQL, template.SQL)
+			}
+			if !slices.Equal(template.Args, tt.wantArgs) {
+				t.Errorf("Expected args %v, got %v", tt.wantArgs, template.Args)
+			}
+		})
+	}
+}
+
+func TestParseTemplates(t *testing.T) {
+	input := `-- Dashboard queries
+/* Latency of the dashboard panels */
+
+-- name: max_min
+SELECT MAX(usage), MIN(usage) FROM cpu_usage
+WHERE host = :hostname AND ts >= :start_time AND ts <= :end_time;
+
+-- name: avg
+SELECT AVG(usage) FROM cpu_usage WHERE host = :hostname;
+`
+
+	templates, err := ParseTemplates(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseTemplates() failed: %v", err)
+	}
+
+	if len(templates) != 2 {
+		t.Fatalf("Expected 2 templates, got %d", len(templates))
+	}
+	if templates[0].Name != "max_min" || templates[1].Name != "avg" {
+		t.Errorf("Unexpected template names %q and %q", templates[0].Name, templates[1].Name)
+	}
+	if !slices.Equal(templates[0].Args, []string{"hostname", "start_time", "end_time"}) {
+		t.Errorf("Unexpected args for max_min: %v", templates[0].Args)
+	}
+	if strings.Contains(templates[0].SQL, ";") {
+		t.Errorf("Expected trailing semicolon to be removed, got %q", templates[0].SQL)
+	}
+}
+
+func TestParseTemplatesSingleQuery(t *testing.T) {
+	templates, err := ParseTemplates(strings.NewReader("SELECT 1 WHERE :hostname = 'a'\n"))
+	if err != nil {
+		t.Fatalf("ParseTemplates() failed: %v", err)
+	}
+
+	if len(templates) != 1 || templates[0].Name != "query" {
+		t.Fatalf("Expected a single template named query, got %+v", templates)
+	}
+}
+
+func TestParseTemplatesErrors(t *testing.T) {
+	tests := map[string]string{
+		"duplicate name":              "-- name: a\nSELECT 1;\n-- name: a\nSELECT 2;\n",
+		"missing name":                "-- name:\nSELECT 1;\n",
+		"empty query":                 "-- name: a\n\n-- name: b\nSELECT 1;\n",
+		"empty file":                  "",
+		"query before the first name": "SELECT 1;\n-- name: a\nSELECT 2;\n",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseTemplates(strings.NewReader(input)); err == nil {
+				t.Errorf("Expected an error for %q", input)
+			}
+		})
+	}
+}
+
+func TestTemplateBind(t *testing.T) {
+	start := time.Date(2017, 1, 1, 8, 59, 22, 0, time.UTC)
+	end := start.Add(time.Hour)
+	params := QueryParams{Hostname: "host_000001", StartTime: start, EndTime: end}
+
+	args, err := DefaultTemplate.bind(params)
+	if err != nil {
+		t.Fatalf("bind() failed: %v", err)
+	}
+	if !slices.Equal(args, []any{"host_000001", start, end}) {
+		t.Errorf("Unexpected arguments %v", args)
+	}
+
+	template, _ := ParseTemplate("unknown", "SELECT :unknown")
+	if _, err := template.bind(params); err == nil {
+		t.Error("Expected an error for an unknown parameter")
+	}
+}
+
+func TestTemplateKey(t *testing.T) {
+	start := time.Date(2017, 1, 1, 9, 59, 22, 0, time.FixedZone("CET", 3600))
+	params := QueryParams{Hostname: "host_000001", StartTime: start, EndTime: start.Add(time.Hour)}
+
+	key, err := DefaultTemplate.Key(params)
+	if err != nil {
+		t.Fatalf("Key() failed: %v", err)
+	}
+	want := "hostname=host_000001 start_time=2017-01-01T08:59:22Z end_time=2017-01-01T09:59:22Z"
+	if key != want {
+		t.Errorf("Expected key %q, got %q", want, key)
+	}
+}
@@ -0,0 +1,180 @@
+// Package metrics exposes live metrics of a benchmark run in the Prometheus text exposition
+// format, so that a long run can be watched on the same dashboards as the database.
+//
+// It exports the number of queries executed and the rows they returned per query template,
+// the errors per class, the latency histogram per query template and the number of queries
+// in flight per worker:
+//
+//	benchmark_queries_total{template="cpu_usage"} 1204
+//	benchmark_query_errors_total{class="deadline_exceeded"} 3
+//	benchmark_query_duration_seconds_bucket{template="cpu_usage",le="0.005"} 1011
+//	benchmark_in_flight_queries{worker="0"} 1
+//
+// The package is safe for concurrent access.
+package metrics
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DurationBuckets are the upper bounds in seconds of the buckets of the latency histograms
+var DurationBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry holds the metrics of a benchmark run
+type Registry struct {
+	mu        sync.Mutex
+	queries   map[string]uint64 // per template
+	rows      map[string]uint64 // per template
+	errors    map[string]uint64 // per error class
+	durations map[string]*histogram
+	inFlight  map[int]int // per worker
+}
+
+// histogram is a Prometheus histogram of the latency of the successful queries
+type histogram struct {
+	counts []uint64 // per bucket of DurationBuckets, not cumulative
+	count  uint64
+	sum    float64 // seconds
+}
+
+// New creates an empty registry
+func New() *Registry {
+	return &Registry{
+		queries:   make(map[string]uint64),
+		rows:      make(map[string]uint64),
+		errors:    make(map[string]uint64),
+		durations: make(map[string]*histogram),
+		inFlight:  make(map[int]int),
+	}
+}
+
+// QueryStarted marks a query of the worker as in flight
+func (r *Registry) QueryStarted(worker int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.inFlight[worker]++
+}
+
+// QueryFinished marks a query of the worker as no longer in flight
+func (r *Registry) QueryFinished(worker int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.inFlight[worker]--
+}
+
+// ObserveQuery records a successful query of the template
+func (r *Registry) ObserveQuery(template string, duration time.Duration, rows int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.queries[template]++
+	r.rows[template] += uint64(rows)
+
+	h, ok := r.durations[template]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(DurationBuckets))}
+		r.durations[template] = h
+	}
+	seconds := duration.Seconds()
+	if i, _ := slices.BinarySearch(DurationBuckets, seconds); i < len(DurationBuckets) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// ObserveUntimed records a successful query of the template whose latency and rows are
+// not representative, such as a query run with EXPLAIN ANALYZE
+func (r *Registry) ObserveUntimed(template string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.queries[template]++
+}
+
+// ObserveError records a failed query of the template with the error class
+func (r *Registry) ObserveError(template, class string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.queries[template]++
+	r.errors[class]++
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = r.WriteText(w)
+}
+
+// WriteText writes the metrics in the Prometheus text exposition format to the provided output
+func (r *Registry) WriteText(out io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w := bufio.NewWriter(out)
+
+	writeHeader(w, "benchmark_queries_total", "counter", "Queries executed, including the failed ones.")
+	for _, template := range sortedKeys(r.queries) {
+		_, _ = fmt.Fprintf(w, "benchmark_queries_total{template=%s} %d\n", quote(template), r.queries[template])
+	}
+
+	writeHeader(w, "benchmark_query_errors_total", "counter", "Failed queries by error class.")
+	for _, class := range sortedKeys(r.errors) {
+		_, _ = fmt.Fprintf(w, "benchmark_query_errors_total{class=%s} %d\n", quote(class), r.errors[class])
+	}
+
+	writeHeader(w, "benchmark_rows_total", "counter", "Rows returned by the successful queries.")
+	for _, template := range sortedKeys(r.rows) {
+		_, _ = fmt.Fprintf(w, "benchmark_rows_total{template=%s} %d\n", quote(template), r.rows[template])
+	}
+
+	writeHeader(w, "benchmark_query_duration_seconds", "histogram", "Latency of the successful queries.")
+	for _, template := range sortedKeys(r.durations) {
+		h := r.durations[template]
+		label := quote(template)
+		var cumulative uint64
+		for i, bound := range DurationBuckets {
+			cumulative += h.counts[i]
+			_, _ = fmt.Fprintf(w, "benchmark_query_duration_seconds_bucket{template=%s,le=\"%s\"} %d\n",
+				label, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+		}
+		_, _ = fmt.Fprintf(w, "benchmark_query_duration_seconds_bucket{template=%s,le=\"+Inf\"} %d\n", label, h.count)
+		_, _ = fmt.Fprintf(w, "benchmark_query_duration_seconds_sum{template=%s} %s\n", label, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		_, _ = fmt.Fprintf(w, "benchmark_query_duration_seconds_count{template=%s} %d\n", label, h.count)
+	}
+
+	writeHeader(w, "benchmark_in_flight_queries", "gauge", "Queries being executed by each worker.")
+	for _, worker := range sortedKeys(r.inFlight) {
+		_, _ = fmt.Fprintf(w, "benchmark_in_flight_queries{worker=\"%d\"} %d\n", worker, r.inFlight[worker])
+	}
+
+	return w.Flush()
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric
+func writeHeader(w io.Writer, name, typ, help string) {
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// quote returns the label value quoted and escaped as required by the text format
+func quote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+// sortedKeys returns the keys of the map in ascending order, for a stable output
+func sortedKeys[K cmp.Ordered, V any](m map[K]V) []K {
+	return slices.Sorted(maps.Keys(m))
+}
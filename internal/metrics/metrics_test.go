@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteText(t *testing.T) {
+	r := New()
+	r.QueryStarted(0)
+	r.QueryStarted(1)
+	r.QueryFinished(1)
+	r.ObserveQuery("cpu_usage", 3*time.Millisecond, 60)
+	r.ObserveQuery("cpu_usage", 20*time.Second, 60)
+	r.ObserveUntimed("cpu_usage")
+	r.ObserveError("cpu_usage", "deadline_exceeded")
+	r.ObserveError(`by "host"`, "sqlstate_57014")
+
+	var out strings.Builder
+	if err := r.WriteText(&out); err != nil {
+		t.Fatalf("WriteText() failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"# TYPE benchmark_queries_total counter\n",
+		"benchmark_queries_total{template=\"cpu_usage\"} 4\n",
+		"benchmark_queries_total{template=\"by \\\"host\\\"\"} 1\n",
+		"benchmark_query_errors_total{class=\"deadline_exceeded\"} 1\n",
+		"benchmark_query_errors_total{class=\"sqlstate_57014\"} 1\n",
+		"benchmark_rows_total{template=\"cpu_usage\"} 120\n",
+		"# TYPE benchmark_query_duration_seconds histogram\n",
+		"benchmark_query_duration_seconds_bucket{template=\"cpu_usage\",le=\"0.0025\"} 0\n",
+		"benchmark_query_duration_seconds_bucket{template=\"cpu_usage\",le=\"0.005\"} 1\n",
+		"benchmark_query_duration_seconds_bucket{template=\"cpu_usage\",le=\"10\"} 1\n",
+		"benchmark_query_duration_seconds_bucket{template=\"cpu_usage\",le=\"+Inf\"} 2\n",
+		"benchmark_query_duration_seconds_sum{template=\"cpu_usage\"} 20.003\n",
+		"benchmark_query_duration_seconds_count{template=\"cpu_usage\"} 2\n",
+		"benchmark_in_flight_queries{worker=\"0\"} 1\n",
+		"benchmark_in_flight_queries{worker=\"1\"} 0\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in the metrics:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	r := New()
+	r.ObserveQuery("cpu_usage", time.Millisecond, 1)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("Expected the text exposition format content type, got %q", got)
+	}
+	if !strings.Contains(rec.Body.String(), "benchmark_queries_total{template=\"cpu_usage\"} 1\n") {
+		t.Errorf("Unexpected metrics:\n%s", rec.Body.String())
+	}
+}
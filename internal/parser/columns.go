@@ -0,0 +1,150 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sandinv/benchmark/internal/database"
+)
+
+// Names of the columns mapped to the dedicated QueryParams fields
+const (
+	columnHostname  = "hostname"
+	columnStartTime = "start_time"
+	columnEndTime   = "end_time"
+	columnIssueTime = "issue_time"
+)
+
+// columnType is the type a column value is converted to before being passed to the queries
+type columnType string
+
+const (
+	typeString columnType = "string"
+	typeInt    columnType = "int"
+	typeFloat  columnType = "float"
+	typeBool   columnType = "bool"
+	typeTime   columnType = "time"
+)
+
+// column describes a column of the input
+type column struct {
+	name string
+	typ  columnType
+}
+
+// defaultColumns are the columns of inputs whose header does not name any known column
+var defaultColumns = []column{
+	{name: columnHostname, typ: typeString},
+	{name: columnStartTime, typ: typeTime},
+	{name: columnEndTime, typ: typeTime},
+}
+
+// parseHeader maps the header fields to columns.
+//
+// Each field is a column name optionally followed by its type, e.g. "limit:int".
+// The known columns (hostname, start_time, end_time and issue_time) have a fixed type, and other
+// columns are strings unless typed. A header that does not name any known column is
+// ignored and the columns are mapped by position to hostname, start_time and end_time.
+func parseHeader(header []string) ([]column, error) {
+	columns := make([]column, 0, len(header))
+	seen := make(map[string]bool, len(header))
+	known := false
+
+	for _, field := range header {
+		name, typ, typed := strings.Cut(strings.TrimSpace(field), ":")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("empty column name in header")
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate column %q in header", name)
+		}
+		seen[name] = true
+
+		col := column{name: name, typ: typeString}
+		builtin := true
+		switch name {
+		case columnHostname:
+		case columnStartTime, columnEndTime, columnIssueTime:
+			col.typ = typeTime
+		default:
+			builtin = false
+		}
+		known = known || builtin
+
+		if typed {
+			t := columnType(strings.TrimSpace(typ))
+			switch t {
+			case typeString, typeInt, typeFloat, typeBool, typeTime:
+			default:
+				return nil, fmt.Errorf("column %q: unknown type %q", name, t)
+			}
+			if builtin && t != col.typ {
+				return nil, fmt.Errorf("column %q must be of type %q", name, col.typ)
+			}
+			col.typ = t
+		}
+
+		columns = append(columns, col)
+	}
+
+	if !known && len(header) == len(defaultColumns) {
+		return defaultColumns, nil
+	}
+
+	return columns, nil
+}
+
+// mapRecord converts the fields of a record to QueryParams according to the columns,
+// parsing the time columns with the time parser
+func mapRecord(columns []column, timeParser *TimeParser, record []string) (database.QueryParams, error) {
+
+	// Records that do not have as many fields as columns are skipped and logged
+	if len(record) != len(columns) {
+		return database.QueryParams{}, fmt.Errorf("invalid record: expected %d fields, got %d", len(columns), len(record))
+	}
+
+	var params database.QueryParams
+	for i, col := range columns {
+		value, err := convert(col.typ, timeParser, record[i])
+		if err != nil {
+			return database.QueryParams{}, fmt.Errorf("invalid %s: %w", strings.ReplaceAll(col.name, "_", " "), err)
+		}
+
+		switch col.name {
+		case columnHostname:
+			params.Hostname = value.(string)
+		case columnStartTime:
+			params.StartTime = value.(time.Time)
+		case columnEndTime:
+			params.EndTime = value.(time.Time)
+		case columnIssueTime:
+			params.IssueTime = value.(time.Time)
+		default:
+			if params.Values == nil {
+				params.Values = make(map[string]any, len(columns)-i)
+			}
+			params.Values[col.name] = value
+		}
+	}
+
+	return params, nil
+}
+
+// convert parses a field to the column type
+func convert(typ columnType, timeParser *TimeParser, field string) (any, error) {
+	switch typ {
+	case typeInt:
+		return strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+	case typeFloat:
+		return strconv.ParseFloat(strings.TrimSpace(field), 64)
+	case typeBool:
+		return strconv.ParseBool(strings.TrimSpace(field))
+	case typeTime:
+		return timeParser.Parse(field)
+	default:
+		return field, nil
+	}
+}
@@ -0,0 +1,214 @@
+package parser
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sandinv/benchmark/internal/database"
+)
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  []string
+		want    []column
+		wantErr bool
+	}{
+		{
+			name:   "known columns",
+			header: []string{"hostname", "start_time", "end_time"},
+			want:   defaultColumns,
+		},
+		{
+			name:   "reordered and extra columns",
+			header: []string{"end_time", "region", "limit:int", "hostname", "start_time"},
+			want: []column{
+				{name: "end_time", typ: typeTime},
+				{name: "region", typ: typeString},
+				{name: "limit", typ: typeInt},
+				{name: "hostname", typ: typeString},
+				{name: "start_time", typ: typeTime},
+			},
+		},
+		{
+			name:   "unknown header mapped by position",
+			header: []string{"host", "from", "to"},
+			want:   defaultColumns,
+		},
+		{
+			name:   "typed known column",
+			header: []string{"hostname:string", "start_time:time"},
+			want: []column{
+				{name: "hostname", typ: typeString},
+				{name: "start_time", typ: typeTime},
+			},
+		},
+		{
+			name:    "duplicate column",
+			header:  []string{"hostname", "hostname"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown type",
+			header:  []string{"hostname", "limit:integer"},
+			wantErr: true,
+		},
+		{
+			name:    "known column with another type",
+			header:  []string{"hostname", "start_time:int"},
+			wantErr: true,
+		},
+		{
+			name:    "empty column name",
+			header:  []string{"hostname", ""},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseHeader(tt.header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseHeader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseHeader() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapRecordTypedValues(t *testing.T) {
+	columns, err := parseHeader([]string{"start_time", "hostname", "limit:int", "ratio:float", "cached:bool", "since:time", "region", "end_time"})
+	if err != nil {
+		t.Fatalf("parseHeader() failed: %v", err)
+	}
+
+	params, err := mapRecord(columns, DefaultTimeParser, []string{
+		"2017-01-01 08:59:22", "host_000001", "10", "0.5", "true", "2016-12-31 00:00:00", "eu-west", "2017-01-01 09:59:22",
+	})
+	if err != nil {
+		t.Fatalf("mapRecord() failed: %v", err)
+	}
+
+	if params.Hostname != "host_000001" {
+		t.Errorf("Expected hostname host_000001, got %q", params.Hostname)
+	}
+	if want := time.Date(2017, 1, 1, 8, 59, 22, 0, time.UTC); !params.StartTime.Equal(want) {
+		t.Errorf("Expected start time %v, got %v", want, params.StartTime)
+	}
+	if want := time.Date(2017, 1, 1, 9, 59, 22, 0, time.UTC); !params.EndTime.Equal(want) {
+		t.Errorf("Expected end time %v, got %v", want, params.EndTime)
+	}
+
+	want := map[string]any{
+		"limit":  int64(10),
+		"ratio":  0.5,
+		"cached": true,
+		"since":  time.Date(2016, 12, 31, 0, 0, 0, 0, time.UTC),
+		"region": "eu-west",
+	}
+	for name, value := range want {
+		got, ok := params.Value(name)
+		if !ok || got != value {
+			t.Errorf("Expected %s = %v (%T), got %v (%T)", name, value, value, got, got)
+		}
+	}
+}
+
+func TestMapRecordIssueTime(t *testing.T) {
+	columns, err := parseHeader([]string{"issue_time", "hostname", "start_time", "end_time"})
+	if err != nil {
+		t.Fatalf("parseHeader() failed: %v", err)
+	}
+
+	params, err := mapRecord(columns, DefaultTimeParser, []string{
+		"2017-01-01 10:00:05", "host_000001", "2017-01-01 08:59:22", "2017-01-01 09:59:22",
+	})
+	if err != nil {
+		t.Fatalf("mapRecord() failed: %v", err)
+	}
+
+	if want := time.Date(2017, 1, 1, 10, 0, 5, 0, time.UTC); !params.IssueTime.Equal(want) {
+		t.Errorf("Expected issue time %v, got %v", want, params.IssueTime)
+	}
+	if len(params.Values) != 0 {
+		t.Errorf("Expected no additional values, got %v", params.Values)
+	}
+
+	if _, err := parseHeader([]string{"hostname", "issue_time:int"}); err == nil {
+		t.Error("Expected an error for a non time issue_time column")
+	}
+}
+
+func TestMapRecordInvalidValue(t *testing.T) {
+	columns, _ := parseHeader([]string{"hostname", "limit:int"})
+
+	if _, err := mapRecord(columns, DefaultTimeParser, []string{"host_000001", "ten"}); err == nil {
+		t.Error("Expected an error for an invalid int value")
+	}
+}
+
+func TestParseAndDistributeColumnMapping(t *testing.T) {
+	csvData := `end_time,hostname,dashboard,start_time
+2017-01-01 09:59:22,host_000001,cpu,2017-01-01 08:59:22
+2017-01-01 11:00:00,host_000002,memory,2017-01-01 10:00:00`
+
+	parser := NewCSVParser(strings.NewReader(csvData), true)
+
+	workerChannels := []chan database.QueryParams{make(chan database.QueryParams, 10)}
+	if err := parser.ParseAndDistribute(context.Background(), workerChannels); err != nil {
+		t.Fatalf("ParseAndDistribute failed: %v", err)
+	}
+	close(workerChannels[0])
+
+	var received []database.QueryParams
+	for params := range workerChannels[0] {
+		received = append(received, params)
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 queries, got %d", len(received))
+	}
+	if received[1].Hostname != "host_000002" || received[1].Values["dashboard"] != "memory" {
+		t.Errorf("Unexpected query parameters: %+v", received[1])
+	}
+	if want := time.Date(2017, 1, 1, 10, 0, 0, 0, time.UTC); !received[1].StartTime.Equal(want) {
+		t.Errorf("Expected start time %v, got %v", want, received[1].StartTime)
+	}
+}
+
+func TestParseAndDistributeInvalidHeader(t *testing.T) {
+	parser := NewCSVParser(strings.NewReader("hostname,hostname\nhost_000001,host_000001"), false)
+
+	workerChannels := []chan database.QueryParams{make(chan database.QueryParams, 10)}
+	if err := parser.ParseAndDistribute(context.Background(), workerChannels); err == nil {
+		t.Error("Expected an error for an invalid header")
+	}
+}
+
+func TestMapRecordMissingColumns(t *testing.T) {
+	tests := map[string][]string{
+		"no hostname column": {"host", "start_time", "end_time"},
+		"no time columns":    {"hostname", "start", "end"},
+	}
+
+	for name, header := range tests {
+		t.Run(name, func(t *testing.T) {
+			columns, err := parseHeader(header)
+			if err != nil {
+				t.Fatalf("parseHeader() failed: %v", err)
+			}
+			params, err := mapRecord(columns, DefaultTimeParser, []string{"host_000001", "2017-01-01 08:59:22", "2017-01-01 09:59:22"})
+			if err != nil {
+				t.Fatalf("mapRecord() failed: %v", err)
+			}
+			if _, err := database.DefaultTemplate.Key(params); err == nil {
+				t.Errorf("Expected the default query to miss parameters with header %v, got %+v", header, params)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package parser
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+)
+
+// CountingReader counts the bytes read from an input, so that the progress of a run over
+// it can be reported. The count may be read concurrently with the reads.
+type CountingReader struct {
+	reader io.Reader
+	total  int64
+	read   atomic.Int64
+}
+
+// NewCountingReader creates a reader counting the bytes read from reader.
+// total is the number of bytes expected to be read over the run, 0 when unknown.
+func NewCountingReader(reader io.Reader, total int64) *CountingReader {
+	return &CountingReader{reader: reader, total: total}
+}
+
+func (c *CountingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+// Seek seeks the underlying reader, failing if it is not an io.Seeker.
+// Seeking doesn't change the count, so that the bytes read are added up over
+// every pass of a run looping over the input.
+func (c *CountingReader) Seek(offset int64, whence int) (int64, error) {
+	seeker, ok := c.reader.(io.Seeker)
+	if !ok {
+		return 0, errors.New("input is not seekable")
+	}
+	return seeker.Seek(offset, whence)
+}
+
+// BytesRead returns the number of bytes read so far and the number of bytes
+// expected to be read over the run, 0 when unknown
+func (c *CountingReader) BytesRead() (read, total int64) {
+	return c.read.Load(), c.total
+}
@@ -1,13 +1,19 @@
 // Package parser provides functionality for parsing benchmark input records
-// from CSV file or standard input, and distributing them to worker goroutines for concurrent query execution.
+// from CSV or JSON Lines files or standard input, and distributing them to worker goroutines for concurrent query execution.
 //
 // The parser implements hostname-based affinity routing using FNV-1a hashing to ensure
 // queries for the same hostname are consistently assigned to the same worker.
 //
+// Columns are mapped by the names found in the header: hostname, start_time,
+// end_time and the optional issue_time fill the dedicated QueryParams fields, while any other column is passed
+// to the queries as a parameter named after it, typed with an optional suffix
+// such as "limit:int". Time columns are parsed with a configurable format and time zone.
+//
 // It supports:
-//   - Streaming CSV processing (line-by-line reading)
+//   - Streaming CSV and JSON Lines processing (line-by-line reading)
+//   - A Source interface so the benchmark can consume any stream of query parameters
 //   - Context cancellation for graceful shutdown
-//   - Strict mode: exits immediately on any CSV reading or parsing error
+//   - Strict mode: exits immediately on any reading or parsing error
 //   - Lenient mode (default): logs errors and continues processing
 package parser
 
@@ -15,10 +21,7 @@ import (
 	"context"
 	"encoding/csv"
 	"fmt"
-	"hash/fnv"
 	"io"
-	"log"
-	"time"
 
 	"github.com/sandinv/benchmark/internal/database"
 )
@@ -27,6 +30,9 @@ import (
 type CSVParser struct {
 	reader     *csv.Reader
 	strictMode bool
+	columns    []column
+	headerRead bool
+	timeParser *TimeParser
 }
 
 // NewCSVParser creates a new CSV parser
@@ -34,97 +40,84 @@ func NewCSVParser(input io.Reader, strictMode bool) *CSVParser {
 	return &CSVParser{
 		reader:     csv.NewReader(input),
 		strictMode: strictMode,
+		columns:    defaultColumns,
+		timeParser: DefaultTimeParser,
 	}
 }
 
+// SetTimeParser sets the parser of the time columns, DefaultTimeParser by default
+func (p *CSVParser) SetTimeParser(timeParser *TimeParser) {
+	p.timeParser = timeParser
+}
+
 // ParseAndDistribute reads CSV input and distributes queries to workers based on hostname
 // The records are read line by line to process large files with minimum impact
 func (p *CSVParser) ParseAndDistribute(ctx context.Context, workerChannels []chan database.QueryParams) error {
+	return Distribute(ctx, p, workerChannels)
+}
 
-	// Read and skip header
-	if _, err := p.reader.Read(); err != nil {
-		return fmt.Errorf("failed to read header: %w", err)
-	}
-
-	numWorkers := len(workerChannels)
-
-	// Read and process records
-	for {
-		// Check for context cancellation
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-
-		record, err := p.reader.Read()
-
-		if err == io.EOF {
-			break
-		}
-
-		// Handle errors on reading records
-		if err != nil {
-			if p.strictMode {
-				return fmt.Errorf("error reading CSV record: %w", err)
-			}
-			log.Printf("Error reading CSV record: %v", err)
-			continue
-		}
+// Strict reports whether malformed records abort the run
+func (p *CSVParser) Strict() bool {
+	return p.strictMode
+}
 
-		params, err := p.parseRecord(record)
-		// Handle malformed records
-		if err != nil {
-			if p.strictMode {
-				return fmt.Errorf("error parsing record: %w", err)
-			}
-			log.Printf("Error parsing record: %v", err)
-			continue
+// Next reads the next CSV record and converts it to QueryParams.
+// The header is read on the first call to map the columns by name.
+func (p *CSVParser) Next(_ context.Context) (database.QueryParams, error) {
+	if !p.headerRead {
+		if err := p.readHeader(); err != nil {
+			return database.QueryParams{}, err
 		}
+	}
 
-		// Assign to worker based on hostname hash
-		// This ensures the same hostname always goes to the same worker
-		workerID := hostnameHash(params.Hostname) % numWorkers
+	record, err := p.reader.Read()
+	if err == io.EOF {
+		return database.QueryParams{}, io.EOF
+	}
 
-		// Try to send to worker channel, but respect context cancellation
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case workerChannels[workerID] <- params:
-		}
+	// Handle errors on reading records
+	if err != nil {
+		return database.QueryParams{}, &RecordError{Err: fmt.Errorf("error reading CSV record: %w", err)}
 	}
 
-	return nil
-}
+	params, err := p.parseRecord(record)
+	if err != nil {
+		return database.QueryParams{}, &RecordError{Err: fmt.Errorf("error parsing record: %w", err)}
+	}
 
-// hostnameHash returns a hash of the hostname for worker assignment
-func hostnameHash(hostname string) int {
-	h := fnv.New32a() // FNV-1a is fast and has good distribution
-	h.Write([]byte(hostname))
-	return int(h.Sum32() & 0x7FFFFFFF) // ensure non-negative int32
+	return params, nil
 }
 
-// parseRecord converts a CSV record to QueryParams
-func (p *CSVParser) parseRecord(record []string) (database.QueryParams, error) {
-
-	// Records that do have more or less than 3 fields are skipped and logged
-	if len(record) != 3 {
-		return database.QueryParams{}, fmt.Errorf("invalid record: expected 3 fields, got %d", len(record))
+// Columns returns the names of the columns the records are mapped to, reading the header
+// if no record was read yet
+func (p *CSVParser) Columns() ([]string, error) {
+	if !p.headerRead {
+		if err := p.readHeader(); err != nil {
+			return nil, err
+		}
 	}
 
-	startTime, err := time.Parse("2006-01-02 15:04:05", record[1])
-	if err != nil {
-		return database.QueryParams{}, fmt.Errorf("invalid start time: %w", err)
+	names := make([]string, len(p.columns))
+	for i, col := range p.columns {
+		names[i] = col.name
 	}
+	return names, nil
+}
 
-	endTime, err := time.Parse("2006-01-02 15:04:05", record[2])
+// readHeader reads the header and maps the columns by name
+func (p *CSVParser) readHeader() error {
+	header, err := p.reader.Read()
 	if err != nil {
-		return database.QueryParams{}, fmt.Errorf("invalid end time: %w", err)
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	if p.columns, err = parseHeader(header); err != nil {
+		return fmt.Errorf("invalid header: %w", err)
 	}
+	p.headerRead = true
+	return nil
+}
 
-	return database.QueryParams{
-		Hostname:  record[0],
-		StartTime: startTime,
-		EndTime:   endTime,
-	}, nil
+// parseRecord converts a CSV record to QueryParams
+func (p *CSVParser) parseRecord(record []string) (database.QueryParams, error) {
+	return mapRecord(p.columns, p.timeParser, record)
 }
@@ -0,0 +1,179 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sandinv/benchmark/internal/database"
+)
+
+// maxJSONLineSize is the size of the longest JSON Lines record accepted, longer lines
+// being malformed records
+const maxJSONLineSize = 1024 * 1024
+
+// JSONLParser parses JSON Lines input, one JSON object per line, and extracts query parameters.
+//
+// The hostname, start_time, end_time and issue_time keys fill the dedicated QueryParams fields, the
+// times being strings or numbers parsed with the time parser. Any other key is passed to
+// the queries as a parameter named after it, keeping its JSON type: strings, booleans,
+// integers (int64) or floats (float64). Empty lines are ignored.
+type JSONLParser struct {
+	reader     *bufio.Reader
+	buffer     []byte // current line
+	strictMode bool
+	timeParser *TimeParser
+	line       int
+}
+
+// NewJSONLParser creates a new JSON Lines parser
+func NewJSONLParser(input io.Reader, strictMode bool) *JSONLParser {
+	return &JSONLParser{
+		reader:     bufio.NewReaderSize(input, 64*1024),
+		strictMode: strictMode,
+		timeParser: DefaultTimeParser,
+	}
+}
+
+// SetTimeParser sets the parser of the time fields, DefaultTimeParser by default
+func (p *JSONLParser) SetTimeParser(timeParser *TimeParser) {
+	p.timeParser = timeParser
+}
+
+// Strict reports whether malformed records abort the run
+func (p *JSONLParser) Strict() bool {
+	return p.strictMode
+}
+
+// Next reads the next non empty line and converts it to QueryParams
+func (p *JSONLParser) Next(_ context.Context) (database.QueryParams, error) {
+	for {
+		line, tooLong, err := p.readLine()
+		if err == io.EOF {
+			return database.QueryParams{}, io.EOF
+		}
+		if err != nil {
+			return database.QueryParams{}, fmt.Errorf("error reading JSON Lines input after line %d: %w", p.line, err)
+		}
+		p.line++
+		if tooLong {
+			return database.QueryParams{}, &RecordError{Err: fmt.Errorf("error parsing record on line %d: line longer than %d bytes", p.line, maxJSONLineSize)}
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		params, err := p.parseLine(line)
+		if err != nil {
+			return database.QueryParams{}, &RecordError{Err: fmt.Errorf("error parsing record on line %d: %w", p.line, err)}
+		}
+		return params, nil
+	}
+}
+
+// readLine reads the next line, including its line feed. The lines longer than
+// maxJSONLineSize are read to their end but not kept, and reported as too long.
+func (p *JSONLParser) readLine() (line []byte, tooLong bool, err error) {
+	p.buffer = p.buffer[:0]
+	size := 0
+	for {
+		chunk, err := p.reader.ReadSlice('\n')
+		size += len(chunk)
+		if size > maxJSONLineSize+1 {
+			tooLong, p.buffer = true, p.buffer[:0]
+		} else {
+			p.buffer = append(p.buffer, chunk...)
+		}
+
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+		case err == io.EOF && size > 0:
+			// Last line without line feed
+			return p.buffer, tooLong, nil
+		case err != nil:
+			return nil, false, err
+		default:
+			return p.buffer, tooLong, nil
+		}
+	}
+}
+
+// parseLine converts a JSON object to QueryParams
+func (p *JSONLParser) parseLine(line []byte) (database.QueryParams, error) {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+
+	var object map[string]any
+	if err := decoder.Decode(&object); err != nil {
+		return database.QueryParams{}, fmt.Errorf("invalid JSON object: %w", err)
+	}
+	if decoder.More() {
+		return database.QueryParams{}, fmt.Errorf("invalid JSON object: unexpected data after the object")
+	}
+
+	var params database.QueryParams
+	for key, value := range object {
+		var err error
+		switch key {
+		case columnHostname:
+			hostname, ok := value.(string)
+			if !ok {
+				return database.QueryParams{}, fmt.Errorf("invalid hostname: expected a string, got %v", value)
+			}
+			params.Hostname = hostname
+		case columnStartTime:
+			params.StartTime, err = p.parseTime(value)
+		case columnEndTime:
+			params.EndTime, err = p.parseTime(value)
+		case columnIssueTime:
+			params.IssueTime, err = p.parseTime(value)
+		default:
+			if params.Values == nil {
+				params.Values = make(map[string]any, len(object))
+			}
+			params.Values[key], err = jsonValue(value)
+		}
+		if err != nil {
+			return database.QueryParams{}, fmt.Errorf("invalid %s: %w", strings.ReplaceAll(key, "_", " "), err)
+		}
+	}
+
+	return params, nil
+}
+
+// parseTime parses a JSON string or number with the time parser
+func (p *JSONLParser) parseTime(value any) (t time.Time, err error) {
+	switch v := value.(type) {
+	case string:
+		return p.timeParser.Parse(v)
+	case json.Number:
+		return p.timeParser.Parse(v.String())
+	default:
+		return t, fmt.Errorf("expected a string or a number, got %v", value)
+	}
+}
+
+// jsonValue converts a decoded JSON value to a query parameter.
+// Numbers become int64 when they are integers and float64 otherwise.
+func jsonValue(value any) (any, error) {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	case map[string]any, []any:
+		return nil, fmt.Errorf("nested objects and arrays are not supported")
+	default:
+		// string, bool or nil
+		return v, nil
+	}
+}
@@ -0,0 +1,264 @@
+package parser
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sandinv/benchmark/internal/database"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		wantErr bool
+	}{
+		{
+			name: "valid record",
+			line: `{"hostname": "host_000001", "start_time": "2017-01-01 08:59:22", "end_time": "2017-01-01 09:59:22"}`,
+		},
+		{
+			name: "extra values",
+			line: `{"hostname": "host_000001", "start_time": "2017-01-01 08:59:22", "end_time": "2017-01-01 09:59:22", "limit": 10}`,
+		},
+		{
+			name:    "invalid JSON",
+			line:    `{"hostname": "host_000001"`,
+			wantErr: true,
+		},
+		{
+			name:    "not an object",
+			line:    `["host_000001", "2017-01-01 08:59:22", "2017-01-01 09:59:22"]`,
+			wantErr: true,
+		},
+		{
+			name:    "trailing data",
+			line:    `{"hostname": "host_000001"} {}`,
+			wantErr: true,
+		},
+		{
+			name:    "numeric hostname",
+			line:    `{"hostname": 1, "start_time": "2017-01-01 08:59:22", "end_time": "2017-01-01 09:59:22"}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid start time",
+			line:    `{"hostname": "host_000001", "start_time": "invalid-date", "end_time": "2017-01-01 09:59:22"}`,
+			wantErr: true,
+		},
+		{
+			name:    "boolean end time",
+			line:    `{"hostname": "host_000001", "start_time": "2017-01-01 08:59:22", "end_time": true}`,
+			wantErr: true,
+		},
+		{
+			name:    "nested value",
+			line:    `{"hostname": "host_000001", "tags": {"region": "eu"}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewJSONLParser(nil, false)
+			params, err := parser.parseLine([]byte(tt.line))
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseLine() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !tt.wantErr && params.Hostname != "host_000001" {
+				t.Errorf("Expected hostname %q, got %q", "host_000001", params.Hostname)
+			}
+		})
+	}
+}
+
+func TestParseLineValues(t *testing.T) {
+	parser := NewJSONLParser(nil, false)
+	line := `{"hostname": "host_000001", "start_time": "2017-01-01 08:59:22", "end_time": "2017-01-01 09:59:22", "limit": 10, "threshold": 0.5, "region": "eu", "active": true}`
+
+	params, err := parser.parseLine([]byte(line))
+	if err != nil {
+		t.Fatalf("parseLine() failed: %v", err)
+	}
+
+	expectedStart := time.Date(2017, 1, 1, 8, 59, 22, 0, time.UTC)
+	if !params.StartTime.Equal(expectedStart) {
+		t.Errorf("Expected start time %v, got %v", expectedStart, params.StartTime)
+	}
+
+	expected := map[string]any{"limit": int64(10), "threshold": 0.5, "region": "eu", "active": true}
+	if len(params.Values) != len(expected) {
+		t.Fatalf("Expected %d values, got %v", len(expected), params.Values)
+	}
+	for name, want := range expected {
+		if got := params.Values[name]; got != want {
+			t.Errorf("Expected %s = %v (%T), got %v (%T)", name, want, want, got, got)
+		}
+	}
+}
+
+func TestParseLineNumericTimes(t *testing.T) {
+	timeParser, err := NewTimeParser(TimeFormatUnix, "")
+	if err != nil {
+		t.Fatalf("NewTimeParser() failed: %v", err)
+	}
+	parser := NewJSONLParser(nil, false)
+	parser.SetTimeParser(timeParser)
+
+	params, err := parser.parseLine([]byte(`{"hostname": "host_000001", "start_time": 1483261162, "end_time": "1483264762"}`))
+	if err != nil {
+		t.Fatalf("parseLine() failed: %v", err)
+	}
+
+	expectedStart := time.Date(2017, 1, 1, 8, 59, 22, 0, time.UTC)
+	expectedEnd := time.Date(2017, 1, 1, 9, 59, 22, 0, time.UTC)
+	if !params.StartTime.Equal(expectedStart) {
+		t.Errorf("Expected start time %v, got %v", expectedStart, params.StartTime)
+	}
+	if !params.EndTime.Equal(expectedEnd) {
+		t.Errorf("Expected end time %v, got %v", expectedEnd, params.EndTime)
+	}
+}
+
+// distributeJSONL runs the parser over the data and returns the number of records received
+func distributeJSONL(t *testing.T, data string, strictMode bool) (int, error) {
+	t.Helper()
+
+	parser := NewJSONLParser(strings.NewReader(data), strictMode)
+
+	numWorkers := 3
+	workerChannels := make([]chan database.QueryParams, numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		workerChannels[i] = make(chan database.QueryParams, 10)
+	}
+
+	// Parse in a goroutine
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- Distribute(context.Background(), parser, workerChannels)
+		for i := 0; i < numWorkers; i++ {
+			close(workerChannels[i])
+		}
+	}()
+
+	// Collect results, checking the hostname affinity
+	totalReceived := 0
+	for i := 0; i < numWorkers; i++ {
+		for params := range workerChannels[i] {
+			if want := hostnameHash(params.Hostname) % numWorkers; want != i {
+				t.Errorf("Hostname %q sent to worker %d, expected %d", params.Hostname, i, want)
+			}
+			totalReceived++
+		}
+	}
+
+	return totalReceived, <-errChan
+}
+
+func TestJSONLDistributeValid(t *testing.T) {
+	data := `{"hostname": "host_000001", "start_time": "2017-01-01 08:59:22", "end_time": "2017-01-01 09:59:22"}
+
+{"hostname": "host_000002", "start_time": "2017-01-01 10:00:00", "end_time": "2017-01-01 11:00:00"}
+{"hostname": "host_000003", "start_time": "2017-01-01 12:00:00", "end_time": "2017-01-01 13:00:00"}
+`
+
+	received, err := distributeJSONL(t, data, false)
+	if err != nil {
+		t.Errorf("Distribute failed: %v", err)
+	}
+
+	// The empty line is ignored
+	if received != 3 {
+		t.Errorf("Expected 3 queries, got %d", received)
+	}
+}
+
+func TestJSONLDistributeStrictMode(t *testing.T) {
+	data := `{"hostname": "host_000001", "start_time": "2017-01-01 08:59:22", "end_time": "2017-01-01 09:59:22"}
+{"hostname": "host_000002", "start_time": "invalid-date", "end_time": "2017-01-01 11:00:00"}
+{"hostname": "host_000003", "start_time": "2017-01-01 12:00:00", "end_time": "2017-01-01 13:00:00"}`
+
+	_, err := distributeJSONL(t, data, true)
+	if err == nil {
+		t.Fatal("Expected error in strict mode with invalid record, got nil")
+	}
+	if !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected the error to report line 2, got: %v", err)
+	}
+}
+
+func TestJSONLDistributeLenientMode(t *testing.T) {
+	data := `{"hostname": "host_000001", "start_time": "2017-01-01 08:59:22", "end_time": "2017-01-01 09:59:22"}
+not json
+{"hostname": "host_000003", "start_time": "2017-01-01 12:00:00", "end_time": "2017-01-01 13:00:00"}`
+
+	received, err := distributeJSONL(t, data, false)
+	if err != nil {
+		t.Errorf("Lenient mode should not error, got: %v", err)
+	}
+
+	// Should receive 2 valid records (skipping the invalid one)
+	if received != 2 {
+		t.Errorf("Expected 2 valid queries, got %d", received)
+	}
+}
+
+func TestJSONLDistributeLineTooLong(t *testing.T) {
+	data := `{"hostname": "host_000001", "start_time": "2017-01-01 08:59:22", "end_time": "2017-01-01 09:59:22"}
+{"hostname": "` + strings.Repeat("x", maxJSONLineSize) + `"}
+{"hostname": "host_000003", "start_time": "2017-01-01 12:00:00", "end_time": "2017-01-01 13:00:00"}`
+
+	// Lines over the size limit are skipped in lenient mode
+	received, err := distributeJSONL(t, data, false)
+	if err != nil {
+		t.Errorf("Lenient mode should not error, got: %v", err)
+	}
+	if received != 2 {
+		t.Errorf("Expected 2 valid queries, got %d", received)
+	}
+
+	_, err = distributeJSONL(t, data, true)
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected an error on line 2 in strict mode, got: %v", err)
+	}
+}
+
+func TestJSONLDistributeContextCancellation(t *testing.T) {
+	data := `{"hostname": "host_000001", "start_time": "2017-01-01 08:59:22", "end_time": "2017-01-01 09:59:22"}`
+
+	parser := NewJSONLParser(strings.NewReader(data), false)
+
+	workerChannels := []chan database.QueryParams{make(chan database.QueryParams, 10)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel() // Cancel immediately
+
+	err := Distribute(ctx, parser, workerChannels)
+
+	// Should return context error
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled error, got: %v", err)
+	}
+}
+
+func TestParseLineMissingKeys(t *testing.T) {
+	lines := []string{
+		`{"host": "host_000001", "start_time": "2017-01-01 08:59:22", "end_time": "2017-01-01 09:59:22"}`,
+		`{"hostname": "host_000001", "start": "2017-01-01 08:59:22", "end": "2017-01-01 09:59:22"}`,
+	}
+
+	for _, line := range lines {
+		params, err := NewJSONLParser(nil, false).parseLine([]byte(line))
+		if err != nil {
+			t.Fatalf("parseLine() failed: %v", err)
+		}
+		if _, err := database.DefaultTemplate.Key(params); err == nil {
+			t.Errorf("Expected the default query to miss parameters for %s", line)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sandinv/benchmark/internal/database"
+)
+
+// LoopSource reads the input of a source over and over, opening a new source each time the
+// previous one is exhausted, until the iteration or the time budget is spent.
+// Each pass over the input is an iteration, and the time budget starts with the first record.
+type LoopSource struct {
+	open       func() (Source, error)
+	iterations int           // 0 for unlimited iterations
+	duration   time.Duration // 0 for no time limit
+
+	current  Source
+	deadline time.Time
+	passes   int
+	records  int // records read in the current pass
+}
+
+// NewLoopSource creates a source looping over the sources returned by open, each one
+// reading the input from the start. An iterations of 0 loops until the duration has
+// elapsed, and a duration of 0 loops for the given number of iterations.
+func NewLoopSource(open func() (Source, error), iterations int, duration time.Duration) (*LoopSource, error) {
+	if iterations < 0 || duration < 0 {
+		return nil, fmt.Errorf("iterations and duration must be equal or greater than 0")
+	}
+	if iterations == 0 && duration == 0 {
+		return nil, fmt.Errorf("either iterations or duration must be set")
+	}
+
+	current, err := open()
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoopSource{
+		open:       open,
+		iterations: iterations,
+		duration:   duration,
+		current:    current,
+		passes:     1,
+	}, nil
+}
+
+// Next returns the next record of the current pass, starting a new pass at the end of the
+// input, and io.EOF once the budget is spent or a pass did not return any record
+func (l *LoopSource) Next(ctx context.Context) (database.QueryParams, error) {
+	if l.deadline.IsZero() && l.duration > 0 {
+		l.deadline = time.Now().Add(l.duration)
+	}
+
+	for {
+		if !l.deadline.IsZero() && !time.Now().Before(l.deadline) {
+			return database.QueryParams{}, io.EOF
+		}
+
+		params, err := l.current.Next(ctx)
+		if err == nil {
+			l.records++
+		}
+		if err != io.EOF {
+			return params, err
+		}
+
+		// An input without valid records would loop forever
+		if l.records == 0 || (l.iterations > 0 && l.passes >= l.iterations) {
+			return database.QueryParams{}, io.EOF
+		}
+
+		if l.current, err = l.open(); err != nil {
+			return database.QueryParams{}, fmt.Errorf("couldn't rewind the input: %w", err)
+		}
+		l.passes++
+		l.records = 0
+	}
+}
+
+// Strict reports whether malformed records abort the run
+func (l *LoopSource) Strict() bool {
+	return l.current.Strict()
+}
+
+// Duration returns the time budget of the source, 0 without one
+func (l *LoopSource) Duration() time.Duration {
+	return l.duration
+}
+
+// Passes returns the number of passes over the input started so far
+func (l *LoopSource) Passes() int {
+	return l.passes
+}
+
+// Rewindable returns a reader of the input that can be read from the start again with Seek.
+// Seekable inputs such as files are returned as is, while other inputs such as pipes are
+// buffered in memory, failing if they are larger than maxSize bytes.
+func Rewindable(input io.Reader, maxSize int64) (io.ReadSeeker, error) {
+	if seeker, ok := input.(io.ReadSeeker); ok {
+		if _, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			return seeker, nil
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(input, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't buffer the input: %w", err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("input larger than %d MiB can't be buffered to be read several times, use a file", maxSize>>20)
+	}
+
+	return bytes.NewReader(data), nil
+}
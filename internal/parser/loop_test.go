@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+const loopCSV = `hostname,start_time,end_time
+host_000001,2017-01-01 08:59:22,2017-01-01 09:59:22
+host_000002,2017-01-01 10:00:00,2017-01-01 11:00:00
+host_000003,2017-01-01 12:00:00,2017-01-01 13:00:00`
+
+// newLoopSource loops over the CSV data
+func newLoopSource(t *testing.T, data string, iterations int, duration time.Duration) *LoopSource {
+	t.Helper()
+
+	input, err := Rewindable(strings.NewReader(data), 1<<20)
+	if err != nil {
+		t.Fatalf("Rewindable() failed: %v", err)
+	}
+
+	loop, err := NewLoopSource(func() (Source, error) {
+		if _, err := input.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return NewCSVParser(input, false), nil
+	}, iterations, duration)
+	if err != nil {
+		t.Fatalf("NewLoopSource() failed: %v", err)
+	}
+	return loop
+}
+
+// countRecords reads the source until io.EOF and returns the number of records
+func countRecords(t *testing.T, source Source) int {
+	t.Helper()
+
+	records := 0
+	for {
+		_, err := NextRecord(context.Background(), source)
+		if err == io.EOF {
+			return records
+		}
+		if err != nil {
+			t.Fatalf("NextRecord() failed: %v", err)
+		}
+		records++
+	}
+}
+
+func TestLoopSourceIterations(t *testing.T) {
+	loop := newLoopSource(t, loopCSV, 3, 0)
+
+	if got := countRecords(t, loop); got != 9 {
+		t.Errorf("Expected 9 records over 3 iterations, got %d", got)
+	}
+	if loop.Passes() != 3 {
+		t.Errorf("Expected 3 passes, got %d", loop.Passes())
+	}
+}
+
+func TestLoopSourceDuration(t *testing.T) {
+	loop := newLoopSource(t, loopCSV, 0, 20*time.Millisecond)
+
+	start := time.Now()
+	records := countRecords(t, loop)
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Expected the loop to last 20ms, took %v", elapsed)
+	}
+	if records <= 3 || loop.Passes() <= 1 {
+		t.Errorf("Expected several passes, got %d records in %d passes", records, loop.Passes())
+	}
+}
+
+func TestLoopSourceWithoutValidRecords(t *testing.T) {
+	// An input without valid records ends the loop instead of spinning
+	data := "hostname,start_time,end_time\nhost_000001,invalid-date,2017-01-01 09:59:22\n"
+	loop := newLoopSource(t, data, 0, time.Hour)
+
+	if got := countRecords(t, loop); got != 0 {
+		t.Errorf("Expected no records, got %d", got)
+	}
+}
+
+func TestNewLoopSourceInvalidBudget(t *testing.T) {
+	open := func() (Source, error) { return NewCSVParser(strings.NewReader(loopCSV), false), nil }
+
+	if _, err := NewLoopSource(open, 0, 0); err == nil {
+		t.Error("Expected an error without iterations nor duration")
+	}
+	if _, err := NewLoopSource(open, -1, 0); err == nil {
+		t.Error("Expected an error for negative iterations")
+	}
+}
+
+func TestRewindableBuffersUpToLimit(t *testing.T) {
+	// io.MultiReader hides the Seek method of strings.Reader, like a pipe
+	if _, err := Rewindable(io.MultiReader(strings.NewReader(loopCSV)), 10); err == nil {
+		t.Error("Expected an error for an input over the limit")
+	}
+
+	input, err := Rewindable(io.MultiReader(strings.NewReader(loopCSV)), int64(len(loopCSV)))
+	if err != nil {
+		t.Fatalf("Rewindable() failed: %v", err)
+	}
+	data, _ := io.ReadAll(input)
+	if string(data) != loopCSV {
+		t.Errorf("Unexpected buffered input %q", data)
+	}
+}
+
+func TestCountingReader(t *testing.T) {
+	input := NewCountingReader(strings.NewReader("hostname\nhost_000001\n"), 42)
+
+	if _, err := io.ReadAll(input); err != nil {
+		t.Fatalf("ReadAll() failed: %v", err)
+	}
+	if _, err := input.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Seek() failed: %v", err)
+	}
+	if _, err := io.ReadAll(input); err != nil {
+		t.Fatalf("ReadAll() failed: %v", err)
+	}
+
+	if read, total := input.BytesRead(); read != 42 || total != 42 {
+		t.Errorf("Expected 42 of 42 bytes read over both passes, got %d of %d", read, total)
+	}
+
+	if _, err := NewCountingReader(io.MultiReader(), 0).Seek(0, io.SeekStart); err == nil {
+		t.Error("Expected an error seeking an input that is not seekable")
+	}
+}
@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/sandinv/benchmark/internal/database"
+)
+
+// Input formats accepted by the benchmark
+const (
+	FormatCSV   = "csv"   // CSV with a header row
+	FormatJSONL = "jsonl" // JSON Lines, one object per line
+)
+
+// RecordError reports a malformed input record. Such records abort the run in
+// strict mode and are logged and skipped in lenient mode.
+type RecordError struct {
+	Err error
+}
+
+func (e *RecordError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// Source is a stream of query parameters, such as a parsed input file, consumed by
+// the benchmark runner. Implementations are read by a single goroutine.
+type Source interface {
+	// Next returns the parameters of the next record, io.EOF once the source is
+	// exhausted and a *RecordError for a malformed record. Any other error is fatal.
+	Next(ctx context.Context) (database.QueryParams, error)
+
+	// Strict reports whether malformed records abort the run instead of being skipped
+	Strict() bool
+}
+
+// NewSource creates the parser of the input format reading from input.
+// A nil timeParser defaults to DefaultTimeParser.
+func NewSource(format string, input io.Reader, strictMode bool, timeParser *TimeParser) (Source, error) {
+	if timeParser == nil {
+		timeParser = DefaultTimeParser
+	}
+
+	switch format {
+	case FormatCSV:
+		csvParser := NewCSVParser(input, strictMode)
+		csvParser.SetTimeParser(timeParser)
+		return csvParser, nil
+	case FormatJSONL:
+		jsonlParser := NewJSONLParser(input, strictMode)
+		jsonlParser.SetTimeParser(timeParser)
+		return jsonlParser, nil
+	default:
+		return nil, fmt.Errorf("unsupported input format %q", format)
+	}
+}
+
+// CheckColumns fails when a template binds a parameter missing from the columns of a CSV
+// source, reading its header, so that a mistake in the header doesn't fail every query.
+// JSON Lines records, whose keys may vary from one record to the next, are not checked.
+func CheckColumns(source Source, templates []database.Template) error {
+	if loop, ok := source.(*LoopSource); ok {
+		source = loop.current
+	}
+	csvParser, ok := source.(*CSVParser)
+	if !ok {
+		return nil
+	}
+
+	columns, err := csvParser.Columns()
+	if err != nil {
+		return err
+	}
+	for _, template := range templates {
+		for _, arg := range template.Args {
+			if !slices.Contains(columns, arg) {
+				return fmt.Errorf("query %q binds :%s, missing from the input columns %s",
+					template.Name, arg, strings.Join(columns, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// Distribute reads every record of the source and sends it to a worker channel based on the hostname.
+// Malformed records abort the distribution when the source is strict and are skipped otherwise,
+// while any other error always aborts it.
+func Distribute(ctx context.Context, source Source, workerChannels []chan database.QueryParams) error {
+
+	for {
+		params, err := NextRecord(ctx, source)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// Try to send to worker channel, but respect context cancellation
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case workerChannels[WorkerIndex(params.Hostname, len(workerChannels))] <- params:
+		}
+	}
+}
+
+// NextRecord returns the next valid record of the source, io.EOF once it is exhausted.
+// Malformed records are returned as errors when the source is strict and logged and
+// skipped otherwise, while any other error is always returned.
+func NextRecord(ctx context.Context, source Source) (database.QueryParams, error) {
+	for {
+		// Check for context cancellation
+		select {
+		case <-ctx.Done():
+			return database.QueryParams{}, ctx.Err()
+		default:
+		}
+
+		params, err := source.Next(ctx)
+		if err == nil || err == io.EOF {
+			return params, err
+		}
+
+		// Handle malformed records
+		var recordErr *RecordError
+		if source.Strict() || !errors.As(err, &recordErr) {
+			return database.QueryParams{}, err
+		}
+		log.Printf("Skipping record: %v", err)
+	}
+}
+
+// WorkerIndex returns the worker assigned to the hostname among numWorkers workers.
+// This ensures the same hostname always goes to the same worker.
+func WorkerIndex(hostname string, numWorkers int) int {
+	return hostnameHash(hostname) % numWorkers
+}
+
+// hostnameHash returns a hash of the hostname for worker assignment
+func hostnameHash(hostname string) int {
+	h := fnv.New32a() // FNV-1a is fast and has good distribution
+	h.Write([]byte(hostname))
+	return int(h.Sum32() & 0x7FFFFFFF) // ensure non-negative int32
+}
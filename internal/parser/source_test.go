@@ -0,0 +1,177 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sandinv/benchmark/internal/database"
+)
+
+// sliceSource is a Source returning the records, then io.EOF
+type sliceSource struct {
+	records []database.QueryParams
+	errs    []error
+	strict  bool
+}
+
+func (s *sliceSource) Next(_ context.Context) (database.QueryParams, error) {
+	if len(s.records) == 0 {
+		return database.QueryParams{}, io.EOF
+	}
+	params, err := s.records[0], s.errs[0]
+	s.records, s.errs = s.records[1:], s.errs[1:]
+	return params, err
+}
+
+func (s *sliceSource) Strict() bool {
+	return s.strict
+}
+
+// distributeSource distributes the source to the workers and returns the number of records received
+func distributeSource(source Source, numWorkers int) (int, error) {
+	workerChannels := make([]chan database.QueryParams, numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		workerChannels[i] = make(chan database.QueryParams, 10)
+	}
+
+	err := Distribute(context.Background(), source, workerChannels)
+
+	totalReceived := 0
+	for i := 0; i < numWorkers; i++ {
+		close(workerChannels[i])
+		for range workerChannels[i] {
+			totalReceived++
+		}
+	}
+	return totalReceived, err
+}
+
+func TestDistributeCustomSource(t *testing.T) {
+	malformed := &RecordError{Err: errors.New("malformed")}
+	newSource := func(strict bool) *sliceSource {
+		return &sliceSource{
+			records: []database.QueryParams{{Hostname: "host_000001"}, {}, {Hostname: "host_000002"}},
+			errs:    []error{nil, malformed, nil},
+			strict:  strict,
+		}
+	}
+
+	received, err := distributeSource(newSource(false), 2)
+	if err != nil {
+		t.Errorf("Lenient source should not error, got: %v", err)
+	}
+	if received != 2 {
+		t.Errorf("Expected 2 valid queries, got %d", received)
+	}
+
+	if _, err := distributeSource(newSource(true), 2); !errors.Is(err, malformed) {
+		t.Errorf("Expected the malformed record error in strict mode, got: %v", err)
+	}
+}
+
+func TestDistributeFatalError(t *testing.T) {
+	// Errors other than RecordError abort the distribution even in lenient mode
+	fatal := errors.New("connection lost")
+	source := &sliceSource{
+		records: []database.QueryParams{{Hostname: "host_000001"}, {}},
+		errs:    []error{nil, fatal},
+	}
+
+	received, err := distributeSource(source, 2)
+	if !errors.Is(err, fatal) {
+		t.Errorf("Expected the fatal error, got: %v", err)
+	}
+	if received != 1 {
+		t.Errorf("Expected 1 query before the error, got %d", received)
+	}
+}
+
+func TestNewSource(t *testing.T) {
+	tests := []struct {
+		format  string
+		input   string
+		wantErr bool
+	}{
+		{format: FormatCSV, input: "hostname,start_time,end_time\nhost_000001,2017-01-01 08:59:22,2017-01-01 09:59:22\n"},
+		{format: FormatJSONL, input: `{"hostname": "host_000001", "start_time": "2017-01-01 08:59:22", "end_time": "2017-01-01 09:59:22"}`},
+		{format: "xml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			source, err := NewSource(tt.format, strings.NewReader(tt.input), true, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if !source.Strict() {
+				t.Error("Expected a strict source")
+			}
+			params, err := source.Next(context.Background())
+			if err != nil {
+				t.Fatalf("Next() failed: %v", err)
+			}
+			if params.Hostname != "host_000001" {
+				t.Errorf("Expected hostname %q, got %q", "host_000001", params.Hostname)
+			}
+			if _, err := source.Next(context.Background()); err != io.EOF {
+				t.Errorf("Expected io.EOF, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestCSVSourceMissingHeader(t *testing.T) {
+	// A missing header is fatal even in lenient mode
+	source := NewCSVParser(strings.NewReader(""), false)
+
+	if _, err := source.Next(context.Background()); err == nil || err == io.EOF {
+		t.Errorf("Expected a header error, got: %v", err)
+	}
+}
+
+func TestCheckColumns(t *testing.T) {
+	templates := []database.Template{database.DefaultTemplate}
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{name: "named columns", input: "hostname,start_time,end_time,limit:int\n"},
+		{name: "positional columns", input: "host,start,end\n"},
+		{name: "missing column", input: "host,start_time,end_time\n", wantErr: true},
+		{name: "extra column only", input: "hostname,start_time,end_time_utc\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := NewSource(FormatCSV, strings.NewReader(tt.input), false, nil)
+			if err != nil {
+				t.Fatalf("NewSource() failed: %v", err)
+			}
+			if err := CheckColumns(source, templates); (err != nil) != tt.wantErr {
+				t.Errorf("CheckColumns() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// The header read by the check still maps the records
+	source, _ := NewSource(FormatCSV, strings.NewReader("hostname,start_time,end_time\nhost_000001,2017-01-01 08:59:22,2017-01-01 09:59:22\n"), true, nil)
+	if err := CheckColumns(source, templates); err != nil {
+		t.Fatalf("CheckColumns() failed: %v", err)
+	}
+	if params, err := source.Next(context.Background()); err != nil || params.Hostname != "host_000001" {
+		t.Errorf("Expected the first record after the check, got %+v (%v)", params, err)
+	}
+
+	jsonl, _ := NewSource(FormatJSONL, strings.NewReader(""), false, nil)
+	if err := CheckColumns(jsonl, templates); err != nil {
+		t.Errorf("Expected JSON Lines sources not to be checked, got %v", err)
+	}
+}
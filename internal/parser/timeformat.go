@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Named time formats accepted by NewTimeParser, any other format is used as a Go time layout
+const (
+	TimeFormatDefault = "default" // 2006-01-02 15:04:05
+	TimeFormatRFC3339 = "rfc3339" // 2006-01-02T15:04:05Z07:00, with optional fractional seconds
+	TimeFormatUnix    = "unix"    // seconds since the Unix epoch, with optional fractional part
+	TimeFormatUnixMs  = "unixms"  // milliseconds since the Unix epoch
+	TimeFormatAuto    = "auto"    // detected for each value
+)
+
+// autoLayouts are the layouts tried in order by the auto format for non numeric values
+var autoLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// TimeParser converts time fields to time.Time according to a format and a time zone.
+//
+// Values that do not carry an offset are interpreted in the time zone, while values
+// with an offset and Unix timestamps denote an absolute instant regardless of it.
+type TimeParser struct {
+	format   string
+	location *time.Location
+}
+
+// DefaultTimeParser parses times with the layout 2006-01-02 15:04:05 in UTC
+var DefaultTimeParser = &TimeParser{format: TimeFormatDefault, location: time.UTC}
+
+// NewTimeParser creates a time parser for the given format and IANA time zone name
+// such as "Europe/Madrid". An empty time zone defaults to UTC.
+func NewTimeParser(format, timezone string) (*TimeParser, error) {
+	location := time.UTC
+	if timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+		}
+	}
+
+	if format == "" {
+		format = TimeFormatDefault
+	}
+
+	return &TimeParser{format: format, location: location}, nil
+}
+
+// Parse converts a field to time.Time
+func (tp *TimeParser) Parse(field string) (time.Time, error) {
+	field = strings.TrimSpace(field)
+
+	switch tp.format {
+	case TimeFormatDefault:
+		return time.ParseInLocation("2006-01-02 15:04:05", field, tp.location)
+	case TimeFormatRFC3339:
+		return time.Parse(time.RFC3339Nano, field)
+	case TimeFormatUnix:
+		return tp.parseEpoch(field, time.Second)
+	case TimeFormatUnixMs:
+		return tp.parseEpoch(field, time.Millisecond)
+	case TimeFormatAuto:
+		return tp.parseAuto(field)
+	default:
+		return time.ParseInLocation(tp.format, field, tp.location)
+	}
+}
+
+// parseEpoch parses a number of units elapsed since the Unix epoch. Integers are converted
+// exactly, while fractional values are parsed as floating point numbers, which keep about
+// 15 significant digits. Timestamps outside the years 1678 to 2262 are out of range.
+func (tp *TimeParser) parseEpoch(field string, unit time.Duration) (time.Time, error) {
+	integer, err := strconv.ParseInt(field, 10, 64)
+	if err == nil {
+		if integer > math.MaxInt64/int64(unit) || integer < math.MinInt64/int64(unit) {
+			return time.Time{}, fmt.Errorf("out of range Unix timestamp %q", field)
+		}
+		return time.Unix(0, integer*int64(unit)).In(tp.location), nil
+	}
+	if errors.Is(err, strconv.ErrRange) {
+		return time.Time{}, fmt.Errorf("out of range Unix timestamp %q", field)
+	}
+
+	value, err := strconv.ParseFloat(field, 64)
+	if err != nil || math.IsInf(value, 0) || math.IsNaN(value) {
+		return time.Time{}, fmt.Errorf("invalid Unix timestamp %q", field)
+	}
+	if nanos := value * float64(unit); nanos >= math.MaxInt64 || nanos < math.MinInt64 {
+		return time.Time{}, fmt.Errorf("out of range Unix timestamp %q", field)
+	}
+
+	// Split the integer part so that the fraction isn't rounded to the precision of the whole value
+	whole, fraction := math.Modf(value)
+	nanos := int64(whole)*int64(unit) + int64(fraction*float64(unit))
+	return time.Unix(0, nanos).In(tp.location), nil
+}
+
+// parseAuto detects the format of the field: numbers are Unix timestamps whose unit
+// is inferred from their magnitude, other values are tried against the known layouts
+func (tp *TimeParser) parseAuto(field string) (time.Time, error) {
+	if value, err := strconv.ParseFloat(field, 64); err == nil {
+		// Magnitude of a current timestamp in each unit: 1e9 s, 1e12 ms, 1e15 µs, 1e18 ns
+		switch magnitude := math.Abs(value); {
+		case magnitude < 1e11:
+			return tp.parseEpoch(field, time.Second)
+		case magnitude < 1e14:
+			return tp.parseEpoch(field, time.Millisecond)
+		case magnitude < 1e17:
+			return tp.parseEpoch(field, time.Microsecond)
+		default:
+			return tp.parseEpoch(field, time.Nanosecond)
+		}
+	}
+
+	for _, layout := range autoLayouts {
+		if t, err := time.ParseInLocation(layout, field, tp.location); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized time %q", field)
+}
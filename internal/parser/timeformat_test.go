@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeParser(t *testing.T) {
+	if _, err := time.LoadLocation("Europe/Madrid"); err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+
+	// 2017-01-01 08:59:22 UTC
+	instant := time.Date(2017, 1, 1, 8, 59, 22, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		format   string
+		timezone string
+		field    string
+		want     time.Time
+		wantErr  bool
+	}{
+		{name: "default layout in UTC", format: TimeFormatDefault, field: "2017-01-01 08:59:22", want: instant},
+		{name: "default layout in a time zone", format: TimeFormatDefault, timezone: "Europe/Madrid", field: "2017-01-01 09:59:22", want: instant},
+		{name: "rfc3339 with offset", format: TimeFormatRFC3339, timezone: "Europe/Madrid", field: "2017-01-01T10:59:22+02:00", want: instant},
+		{name: "rfc3339 with fractional seconds", format: TimeFormatRFC3339, field: "2017-01-01T08:59:22.5Z", want: instant.Add(500 * time.Millisecond)},
+		{name: "rfc3339 without offset", format: TimeFormatRFC3339, field: "2017-01-01T08:59:22", wantErr: true},
+		{name: "unix seconds", format: TimeFormatUnix, timezone: "Europe/Madrid", field: "1483261162", want: instant},
+		{name: "unix fractional seconds", format: TimeFormatUnix, field: "1483261162.25", want: instant.Add(250 * time.Millisecond)},
+		{name: "unix milliseconds", format: TimeFormatUnixMs, field: "1483261162000", want: instant},
+		{name: "invalid unix", format: TimeFormatUnix, field: "yesterday", wantErr: true},
+		{name: "custom layout", format: "02/01/2006 15:04", timezone: "Europe/Madrid", field: "01/01/2017 09:59", want: instant.Add(-22 * time.Second)},
+		{name: "auto seconds", format: TimeFormatAuto, field: "1483261162", want: instant},
+		{name: "auto milliseconds", format: TimeFormatAuto, field: "1483261162000", want: instant},
+		{name: "auto microseconds", format: TimeFormatAuto, field: "1483261162000000", want: instant},
+		{name: "auto nanoseconds", format: TimeFormatAuto, field: "1483261162000000000", want: instant},
+		{name: "auto exact nanoseconds", format: TimeFormatAuto, field: "1700000000123456789", want: time.Unix(1700000000, 123456789)},
+		{name: "unix out of range", format: TimeFormatUnix, field: "9300000000", wantErr: true},
+		{name: "unix out of int64 range", format: TimeFormatUnix, field: "99999999999999999999", wantErr: true},
+		{name: "unix fractional out of range", format: TimeFormatUnix, field: "9300000000.5", wantErr: true},
+		{name: "auto rfc3339", format: TimeFormatAuto, field: "2017-01-01T09:59:22+01:00", want: instant},
+		{name: "auto local time", format: TimeFormatAuto, timezone: "Europe/Madrid", field: "2017-01-01 09:59:22", want: instant},
+		{name: "auto local ISO time", format: TimeFormatAuto, timezone: "Europe/Madrid", field: "2017-01-01T09:59:22", want: instant},
+		{name: "auto unrecognized", format: TimeFormatAuto, field: "Jan 1st", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tp, err := NewTimeParser(tt.format, tt.timezone)
+			if err != nil {
+				t.Fatalf("NewTimeParser() failed: %v", err)
+			}
+
+			got, err := tp.Parse(tt.field)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.field, err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("Parse(%q) = %v, want %v", tt.field, got, tt.want)
+			}
+		})
+	}
+
+	// Unix timestamps are converted to the configured time zone
+	tp, _ := NewTimeParser(TimeFormatUnix, "Europe/Madrid")
+	if got, _ := tp.Parse("1483261162"); got.Location().String() != "Europe/Madrid" {
+		t.Errorf("Expected Unix timestamp in Europe/Madrid, got %v", got.Location())
+	}
+}
+
+func TestNewTimeParserInvalidTimezone(t *testing.T) {
+	if _, err := NewTimeParser(TimeFormatDefault, "Mars/Olympus_Mons"); err == nil {
+		t.Error("Expected an error for an unknown time zone")
+	}
+}
+
+func TestParseRecordTimezone(t *testing.T) {
+	tp, err := NewTimeParser(TimeFormatRFC3339, "")
+	if err != nil {
+		t.Fatalf("NewTimeParser() failed: %v", err)
+	}
+
+	parser := NewCSVParser(nil, false)
+	parser.SetTimeParser(tp)
+
+	params, err := parser.parseRecord([]string{"host_000001", "2017-01-01T09:59:22+01:00", "2017-01-01T10:59:22+01:00"})
+	if err != nil {
+		t.Fatalf("parseRecord() failed: %v", err)
+	}
+
+	if want := time.Date(2017, 1, 1, 8, 59, 22, 0, time.UTC); !params.StartTime.Equal(want) {
+		t.Errorf("Expected start time %v, got %v", want, params.StartTime)
+	}
+}
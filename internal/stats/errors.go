@@ -0,0 +1,70 @@
+package stats
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// unclassifiedError is the class of the errors added without one
+const unclassifiedError = "other"
+
+// ErrorClassSummary holds the failed queries of an error class, such as timeouts or connection failures
+type ErrorClassSummary struct {
+	Class  string
+	Count  int
+	Sample string // message of the first error of the class
+}
+
+// recordFailure adds a failed query to its error class and to the latency of the failed queries
+// Must be called with mutex locked
+func (s *Statistics) recordFailure(sample Sample) {
+	class := sample.ErrorClass
+	if class == "" {
+		class = unclassifiedError
+	}
+	summary, ok := s.errorClasses[class]
+	if !ok {
+		summary = &ErrorClassSummary{Class: class, Sample: sample.Err.Error()}
+		s.errorClasses[class] = summary
+	}
+	summary.Count++
+	s.failed.record(sample.Duration)
+}
+
+// computeErrors calculates the per-class error counts, sorted from the most to the least frequent class
+// Must be called with mutex locked
+func (s *Statistics) computeErrors() {
+	s.Errors = make([]ErrorClassSummary, 0, len(s.errorClasses))
+	for _, summary := range s.errorClasses {
+		s.Errors = append(s.Errors, *summary)
+	}
+	slices.SortFunc(s.Errors, func(a, b ErrorClassSummary) int {
+		if a.Count != b.Count {
+			return cmp.Compare(b.Count, a.Count)
+		}
+		return strings.Compare(a.Class, b.Class)
+	})
+
+	s.failed.compute()
+	s.FailedLatency = s.failed
+}
+
+// printErrors outputs the failed queries per error class and their latency
+func (s *Statistics) printErrors(out io.Writer) {
+	_, _ = fmt.Fprintln(out, "\nErrors:")
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "  Class\tCount\tSample")
+	for _, e := range s.Errors {
+		_, _ = fmt.Fprintf(tw, "  %s\t%d\t%s\n", e.Class, e.Count, e.Sample)
+	}
+	_ = tw.Flush()
+
+	if s.FailedLatency.Queries > 0 {
+		_, _ = fmt.Fprintf(out, "Failed query latency: median %v, P99 %v, maximum %v\n",
+			s.FailedLatency.MedianTime, s.FailedLatency.P99, s.FailedLatency.MaxTime)
+	}
+}
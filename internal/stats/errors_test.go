@@ -0,0 +1,110 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddErrorClasses(t *testing.T) {
+	s := New()
+
+	s.Add(Sample{Hostname: "host_000001", Duration: time.Millisecond})
+	for i := 1; i <= 3; i++ {
+		s.Add(Sample{Hostname: "host_000001", Duration: time.Duration(i) * time.Second,
+			Err: errors.New("context deadline exceeded"), ErrorClass: "deadline_exceeded"})
+	}
+	s.Add(Sample{Hostname: "host_000002", Duration: 2 * time.Millisecond,
+		Err: errors.New(`pq: relation "cpu_usage" does not exist`), ErrorClass: "sqlstate_42P01"})
+	s.Add(Sample{Hostname: "host_000002", Duration: 3 * time.Millisecond,
+		Err: errors.New(`pq: relation "cpu" does not exist`), ErrorClass: "sqlstate_42P01"})
+	s.Add(Sample{Hostname: "host_000002", Duration: 4 * time.Millisecond, Err: errors.New("missing value")})
+	s.Compute()
+
+	want := []ErrorClassSummary{
+		{Class: "deadline_exceeded", Count: 3, Sample: "context deadline exceeded"},
+		{Class: "sqlstate_42P01", Count: 2, Sample: `pq: relation "cpu_usage" does not exist`},
+		{Class: "other", Count: 1, Sample: "missing value"},
+	}
+	if len(s.Errors) != len(want) {
+		t.Fatalf("Expected %d error classes, got %+v", len(want), s.Errors)
+	}
+	for i := range want {
+		if s.Errors[i] != want[i] {
+			t.Errorf("Expected error class %+v, got %+v", want[i], s.Errors[i])
+		}
+	}
+
+	if s.FailedLatency.Queries != 6 || s.FailedLatency.MaxTime != 3*time.Second {
+		t.Errorf("Unexpected failed query latency %+v", s.FailedLatency)
+	}
+	if s.MaxTime != time.Millisecond {
+		t.Errorf("Expected failed queries to be excluded from the latency, got a maximum of %v", s.MaxTime)
+	}
+
+	var out strings.Builder
+	s.Print(&out)
+	for _, want := range []string{"Errors:", "deadline_exceeded  3      context deadline exceeded", "Failed query latency:"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in the report:\n%s", want, out.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := s.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() failed: %v", err)
+	}
+	var report struct {
+		Errors []struct {
+			Class string `json:"class"`
+			Count int    `json:"count"`
+		} `json:"errors"`
+		FailedLatency *struct {
+			Queries int `json:"queries"`
+		} `json:"failed_latency"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid JSON report: %v", err)
+	}
+	if len(report.Errors) != 3 || report.Errors[0].Class != "deadline_exceeded" || report.FailedLatency == nil || report.FailedLatency.Queries != 6 {
+		t.Errorf("Unexpected errors in the JSON report:\n%s", buf.String())
+	}
+}
+
+func TestNoErrorsNotPrinted(t *testing.T) {
+	s := New()
+	s.Add(Sample{Hostname: "host_000001", Duration: time.Millisecond})
+	s.Compute()
+
+	var out strings.Builder
+	s.Print(&out)
+	if strings.Contains(out.String(), "Errors:") {
+		t.Errorf("Expected no errors section without failed queries:\n%s", out.String())
+	}
+}
+
+func TestTimedOutQueries(t *testing.T) {
+	s := New()
+	s.Metadata.QueryTimeout = 30 * time.Second
+	s.Metadata.StatementTimeout = 25 * time.Second
+
+	s.Add(Sample{Hostname: "host_000001", Duration: time.Millisecond})
+	s.Add(Sample{Hostname: "host_000001", Duration: 25 * time.Second,
+		Err: errors.New("pq: canceling statement due to statement timeout"), ErrorClass: "sqlstate_57014", TimedOut: true})
+	s.Add(Sample{Hostname: "host_000001", Duration: 2 * time.Millisecond, Err: errors.New("connection reset by peer"), ErrorClass: "network"})
+	s.Add(Sample{Hostname: "host_000001", Duration: 30 * time.Second, Err: errors.New("context deadline exceeded"), TimedOut: true, Warmup: true})
+	s.Compute()
+
+	if s.TimedOutQueries != 1 || s.FailedQueries != 2 {
+		t.Errorf("Expected 1 timed out query out of 2 failed queries, got %d and %d", s.TimedOutQueries, s.FailedQueries)
+	}
+
+	var out strings.Builder
+	s.Print(&out)
+	if want := "Timed out queries:           1 (query timeout 30s, statement_timeout 25s)"; !strings.Contains(out.String(), want) {
+		t.Errorf("Expected %q in the report:\n%s", want, out.String())
+	}
+}
@@ -0,0 +1,138 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// Plan holds the server-side statistics of a query run with EXPLAIN ANALYZE
+type Plan struct {
+	PlanningTime     time.Duration
+	ExecutionTime    time.Duration
+	Chunks           int   // hypertable chunks scanned
+	SharedHitBlocks  int64 // blocks found in the shared buffers
+	SharedReadBlocks int64 // blocks read from disk or the OS cache
+}
+
+// Distribution holds the order statistics of a count, such as the chunks scanned by each query
+type Distribution struct {
+	Median int64
+	P95    int64
+	P99    int64
+	Max    int64
+}
+
+// Explain summarizes the queries sampled to be run with EXPLAIN ANALYZE, comparing their
+// client-side latency with the time the server spent planning and executing them
+type Explain struct {
+	Rate             float64 // share of the queries sampled
+	Client           Summary // client-side latency of the sampled queries
+	PlanningTime     Summary
+	ExecutionTime    Summary
+	Chunks           Distribution
+	SharedHitBlocks  Distribution
+	SharedReadBlocks Distribution
+
+	// Counts are recorded as durations of one nanosecond per unit
+	chunks recorder
+	hits   recorder
+	reads  recorder
+}
+
+// SetExplainRate enables the statistics of the queries sampled with EXPLAIN ANALYZE at the given rate
+func (s *Statistics) SetExplainRate(rate float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Explain = &Explain{
+		Rate:          rate,
+		Client:        s.newSummary(),
+		PlanningTime:  s.newSummary(),
+		ExecutionTime: s.newSummary(),
+		chunks:        s.newBreakdownRecorder(),
+		hits:          s.newBreakdownRecorder(),
+		reads:         s.newBreakdownRecorder(),
+	}
+}
+
+// recordExplain adds a query run with EXPLAIN ANALYZE to the explain statistics
+// Must be called with mutex locked
+func (s *Statistics) recordExplain(sample Sample) {
+	e := s.Explain
+	if sample.Err != nil {
+		e.Client.recordError()
+		return
+	}
+	e.Client.record(sample.Duration)
+	e.PlanningTime.record(sample.Plan.PlanningTime)
+	e.ExecutionTime.record(sample.Plan.ExecutionTime)
+	e.chunks.Record(time.Duration(sample.Plan.Chunks))
+	e.hits.Record(time.Duration(sample.Plan.SharedHitBlocks))
+	e.reads.Record(time.Duration(sample.Plan.SharedReadBlocks))
+}
+
+// computeExplain calculates the explain statistics
+// Must be called with mutex locked
+func (s *Statistics) computeExplain() {
+	if s.Explain == nil {
+		return
+	}
+	e := s.Explain
+	e.Client.compute()
+	e.PlanningTime.compute()
+	e.ExecutionTime.compute()
+	e.Chunks = newDistribution(e.chunks)
+	e.SharedHitBlocks = newDistribution(e.hits)
+	e.SharedReadBlocks = newDistribution(e.reads)
+}
+
+// newDistribution computes the distribution of the counts recorded as durations
+func newDistribution(counts recorder) Distribution {
+	if counts.Count() == 0 {
+		return Distribution{}
+	}
+	return Distribution{
+		Median: int64(counts.Percentile(50)),
+		P95:    int64(counts.Percentile(95)),
+		P99:    int64(counts.Percentile(99)),
+		Max:    int64(counts.Max()),
+	}
+}
+
+// printExplain outputs the server-side statistics of the sampled queries next to their client-side latency
+func (s *Statistics) printExplain(out io.Writer) {
+	e := s.Explain
+	_, _ = fmt.Fprintf(out, "\nServer-Side Execution (EXPLAIN ANALYZE on %g%% of the queries, %d sampled, %d errors):\n",
+		e.Rate*100, e.Client.Queries, e.Client.Errors)
+	if e.PlanningTime.Queries == 0 {
+		return
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "  Metric\tMedian\tP95\tP99\tMaximum")
+	for _, row := range []struct {
+		name    string
+		summary Summary
+	}{
+		{"Client latency", e.Client},
+		{"Planning time", e.PlanningTime},
+		{"Execution time", e.ExecutionTime},
+	} {
+		_, _ = fmt.Fprintf(tw, "  %s\t%v\t%v\t%v\t%v\n",
+			row.name, row.summary.MedianTime, row.summary.P95, row.summary.P99, row.summary.MaxTime)
+	}
+	for _, row := range []struct {
+		name         string
+		distribution Distribution
+	}{
+		{"Chunks scanned", e.Chunks},
+		{"Shared hit blocks", e.SharedHitBlocks},
+		{"Shared read blocks", e.SharedReadBlocks},
+	} {
+		_, _ = fmt.Fprintf(tw, "  %s\t%d\t%d\t%d\t%d\n",
+			row.name, row.distribution.Median, row.distribution.P95, row.distribution.P99, row.distribution.Max)
+	}
+	_ = tw.Flush()
+}
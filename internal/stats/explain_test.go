@@ -0,0 +1,102 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExplain(t *testing.T) {
+	s := New()
+	s.SetExplainRate(0.01)
+
+	s.Add(Sample{Hostname: "host_000001", Duration: 5 * time.Millisecond})
+	for i := 1; i <= 4; i++ {
+		s.Add(Sample{Hostname: "host_000001", Duration: time.Duration(i) * 10 * time.Millisecond, Explained: true,
+			Plan: Plan{
+				PlanningTime:     time.Duration(i) * time.Millisecond,
+				ExecutionTime:    time.Duration(i) * 5 * time.Millisecond,
+				Chunks:           i,
+				SharedHitBlocks:  int64(i) * 100,
+				SharedReadBlocks: int64(i),
+			}})
+	}
+	s.Add(Sample{Hostname: "host_000001", Duration: time.Second, Explained: true, Err: errors.New("canceling statement")})
+	s.Compute()
+
+	if s.TotalQueries != 1 || len(s.Hosts) != 1 || s.Hosts[0].Queries != 1 {
+		t.Errorf("Expected the sampled queries to be left out of the other statistics, got %d queries", s.TotalQueries)
+	}
+
+	e := s.Explain
+	if e.Client.Queries != 5 || e.Client.Errors != 1 || e.Client.MaxTime != 40*time.Millisecond {
+		t.Errorf("Unexpected client latency %+v", e.Client)
+	}
+	if e.PlanningTime.Queries != 4 || e.PlanningTime.MaxTime != 4*time.Millisecond {
+		t.Errorf("Unexpected planning time %+v", e.PlanningTime)
+	}
+	if e.ExecutionTime.MaxTime != 20*time.Millisecond {
+		t.Errorf("Expected a maximum execution time of 20ms, got %v", e.ExecutionTime.MaxTime)
+	}
+	if e.Chunks.Max != 4 || e.SharedHitBlocks.Max != 400 || e.SharedReadBlocks.Median == 0 {
+		t.Errorf("Unexpected distributions %+v %+v %+v", e.Chunks, e.SharedHitBlocks, e.SharedReadBlocks)
+	}
+
+	var out strings.Builder
+	s.Print(&out)
+	for _, want := range []string{"EXPLAIN ANALYZE on 1% of the queries, 5 sampled, 1 errors", "Planning time", "Chunks scanned"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in the report:\n%s", want, out.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := s.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() failed: %v", err)
+	}
+	var report struct {
+		Explain *struct {
+			Rate   float64 `json:"rate"`
+			Chunks struct {
+				Max int64 `json:"max"`
+			} `json:"chunks"`
+		} `json:"explain"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if report.Explain == nil || report.Explain.Rate != 0.01 || report.Explain.Chunks.Max != 4 {
+		t.Errorf("Unexpected explain section %+v", report.Explain)
+	}
+}
+
+func TestExplainDuringWarmup(t *testing.T) {
+	s := New()
+	s.SetExplainRate(0.01)
+
+	s.Add(Sample{Hostname: "host_000001", Duration: time.Millisecond, Warmup: true})
+	s.Add(Sample{Hostname: "host_000001", Duration: 10 * time.Millisecond, Warmup: true, Explained: true})
+	s.Compute()
+
+	if s.Warmup.Queries != 1 || s.Warmup.MaxTime != time.Millisecond {
+		t.Errorf("Expected the sampled query to be left out of the warm-up statistics, got %+v", s.Warmup)
+	}
+	if s.Explain.Client.Queries != 0 {
+		t.Errorf("Expected the sampled warm-up query to be dropped, got %d explained queries", s.Explain.Client.Queries)
+	}
+}
+
+func TestExplainDisabled(t *testing.T) {
+	s := New()
+	s.Add(Sample{Hostname: "host_000001", Duration: time.Millisecond})
+	s.Compute()
+
+	var out strings.Builder
+	s.Print(&out)
+	if s.Explain != nil || strings.Contains(out.String(), "EXPLAIN") {
+		t.Errorf("Expected no explain statistics unless enabled:\n%s", out.String())
+	}
+}
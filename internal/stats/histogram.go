@@ -0,0 +1,282 @@
+package stats
+
+import (
+	"fmt"
+	"math/bits"
+	"slices"
+	"time"
+)
+
+// Bounds of the precision accepted by NewHistogram, in significant decimal digits
+const (
+	MinPrecision = 1
+	MaxPrecision = 5
+)
+
+// recorder stores query durations and answers order statistics about them
+type recorder interface {
+	Record(duration time.Duration)
+	Count() int
+	Min() time.Duration
+	Max() time.Duration
+	Mean() time.Duration
+	Percentile(p float64) time.Duration
+}
+
+// newRecorder returns an exact recorder when precision is 0 and a histogram otherwise
+func newRecorder(precision int) recorder {
+	if precision == 0 {
+		return &exactRecorder{}
+	}
+	return NewHistogram(precision)
+}
+
+// Histogram records durations in a fixed amount of memory, in the spirit of HdrHistogram.
+//
+// Values are stored with nanosecond resolution in log-linear buckets: every power of two
+// range is split in enough linear sub-buckets to keep the requested number of significant
+// decimal digits. Minimum, maximum and mean are exact, while every percentile reported is
+// within a relative error of 1/(2*10^precision) of the value the exact sort-based method
+// would return, e.g. 0.05% for a precision of 3 digits.
+//
+// Memory usage does not depend on the number of recorded values but on the range they
+// span: only the buckets between the smallest and the largest value are allocated, which
+// is at most a few hundred kilobytes for a precision of 3 digits.
+//
+// Histogram is not safe for concurrent use.
+type Histogram struct {
+	precision int
+	// Each power of two range is split in subBucketHalfCount linear sub-buckets,
+	// except the first one that holds every value below subBucketCount with an exact count.
+	subBucketMagnitude int // log2(subBucketCount)
+	subBucketCount     int64
+	subBucketHalfCount int64
+
+	offset int      // bucket index of counts[0]
+	counts []uint64 // counts of the allocated buckets
+	total  uint64
+	min    time.Duration
+	max    time.Duration
+	sum    time.Duration
+}
+
+// NewHistogram creates a histogram keeping the given number of significant decimal digits.
+// It panics if precision is not between MinPrecision and MaxPrecision.
+func NewHistogram(precision int) *Histogram {
+	if precision < MinPrecision || precision > MaxPrecision {
+		panic(fmt.Sprintf("stats: histogram precision must be between %d and %d, got %d",
+			MinPrecision, MaxPrecision, precision))
+	}
+
+	// Smallest power of two able to tell apart 2*10^precision values in a range
+	largestSingleUnitResolution := int64(2)
+	for range precision {
+		largestSingleUnitResolution *= 10
+	}
+	magnitude := bits.Len64(uint64(largestSingleUnitResolution - 1))
+
+	return &Histogram{
+		precision:          precision,
+		subBucketMagnitude: magnitude,
+		subBucketCount:     1 << magnitude,
+		subBucketHalfCount: 1 << (magnitude - 1),
+	}
+}
+
+// Precision returns the number of significant decimal digits kept by the histogram
+func (h *Histogram) Precision() int {
+	return h.precision
+}
+
+// Record adds a duration to the histogram. Negative durations are recorded as 0.
+func (h *Histogram) Record(duration time.Duration) {
+	duration = max(duration, 0)
+
+	index := h.bucketIndex(int64(duration))
+	switch {
+	case len(h.counts) == 0:
+		h.offset = index
+		h.counts = append(h.counts, 0)
+	case index < h.offset:
+		h.counts = slices.Insert(h.counts, 0, make([]uint64, h.offset-index)...)
+		h.offset = index
+	case index >= h.offset+len(h.counts):
+		h.counts = append(h.counts, make([]uint64, index-h.offset-len(h.counts)+1)...)
+	}
+	h.counts[index-h.offset]++
+
+	if h.total == 0 || duration < h.min {
+		h.min = duration
+	}
+	if duration > h.max {
+		h.max = duration
+	}
+	h.total++
+	h.sum += duration
+}
+
+// Count returns the number of recorded durations
+func (h *Histogram) Count() int {
+	return int(h.total)
+}
+
+// Min returns the smallest recorded duration
+func (h *Histogram) Min() time.Duration {
+	return h.min
+}
+
+// Max returns the largest recorded duration
+func (h *Histogram) Max() time.Duration {
+	return h.max
+}
+
+// Mean returns the average of the recorded durations
+func (h *Histogram) Mean() time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.total)
+}
+
+// Percentile returns the given percentile of the recorded durations using the same
+// linear interpolation between the two nearest ranks as the exact method
+func (h *Histogram) Percentile(p float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+
+	rank := (p / 100.0) * float64(h.total-1)
+	lower := uint64(rank)
+	upper := min(lower+1, h.total-1)
+
+	lowerValue, upperValue := h.valuesAtRanks(lower, upper)
+	fraction := rank - float64(lower)
+	return time.Duration(float64(lowerValue) + fraction*float64(upperValue-lowerValue))
+}
+
+// valuesAtRanks returns the durations at the given 0-based ranks, lower <= upper
+func (h *Histogram) valuesAtRanks(lower, upper uint64) (lowerValue, upperValue time.Duration) {
+	var cumulative uint64
+	found := false
+	for i, count := range h.counts {
+		cumulative += count
+		if !found && cumulative > lower {
+			lowerValue = h.bucketValue(h.offset + i)
+			found = true
+		}
+		if cumulative > upper {
+			upperValue = h.bucketValue(h.offset + i)
+			break
+		}
+	}
+	return lowerValue, upperValue
+}
+
+// bucketIndex returns the index of the bucket holding the value
+func (h *Histogram) bucketIndex(value int64) int {
+	if value < h.subBucketCount {
+		return int(value)
+	}
+	// Power of two range of the value, the first range being the exact one
+	bucket := bits.Len64(uint64(value)) - h.subBucketMagnitude
+	subBucket := value >> bucket
+	return int(int64(bucket)*h.subBucketHalfCount + subBucket)
+}
+
+// bucketValue returns the value representing a bucket: the middle of its range,
+// clamped to the recorded minimum and maximum
+func (h *Histogram) bucketValue(index int) time.Duration {
+	var lowest, width int64
+	if int64(index) < h.subBucketCount {
+		lowest, width = int64(index), 1
+	} else {
+		bucket := (int64(index)-h.subBucketCount)/h.subBucketHalfCount + 1
+		subBucket := int64(index) - bucket*h.subBucketHalfCount
+		lowest, width = subBucket<<bucket, int64(1)<<bucket
+	}
+
+	value := time.Duration(lowest + width/2)
+	return min(max(value, h.min), h.max)
+}
+
+// exactRecorder stores every duration and computes exact order statistics by sorting them
+type exactRecorder struct {
+	durations []time.Duration
+	sorted    bool
+	sum       time.Duration
+}
+
+// Record adds a duration to the recorder
+func (r *exactRecorder) Record(duration time.Duration) {
+	r.durations = append(r.durations, duration)
+	r.sum += duration
+	r.sorted = false
+}
+
+// Count returns the number of recorded durations
+func (r *exactRecorder) Count() int {
+	return len(r.durations)
+}
+
+// Min returns the smallest recorded duration
+func (r *exactRecorder) Min() time.Duration {
+	r.sort()
+	if len(r.durations) == 0 {
+		return 0
+	}
+	return r.durations[0]
+}
+
+// Max returns the largest recorded duration
+func (r *exactRecorder) Max() time.Duration {
+	r.sort()
+	if len(r.durations) == 0 {
+		return 0
+	}
+	return r.durations[len(r.durations)-1]
+}
+
+// Mean returns the average of the recorded durations
+func (r *exactRecorder) Mean() time.Duration {
+	if len(r.durations) == 0 {
+		return 0
+	}
+	return r.sum / time.Duration(len(r.durations))
+}
+
+// Percentile returns the given percentile of the recorded durations
+func (r *exactRecorder) Percentile(p float64) time.Duration {
+	r.sort()
+	return percentile(r.durations, p)
+}
+
+// sort sorts the durations if new ones were recorded since the last call
+func (r *exactRecorder) sort() {
+	if !r.sorted {
+		slices.Sort(r.durations)
+		r.sorted = true
+	}
+}
+
+// percentile calculates the given percentile from sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	// Use linear interpolation method
+	n := float64(len(sorted))
+	rank := (p / 100.0) * (n - 1)
+	lower := int(rank)
+	upper := lower + 1
+
+	// Handle edge cases
+	if upper >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+
+	// Linear interpolation between the two nearest values
+	fraction := rank - float64(lower)
+	return time.Duration(float64(sorted[lower]) +
+		fraction*float64(sorted[upper]-sorted[lower]))
+}
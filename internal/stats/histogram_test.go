@@ -0,0 +1,145 @@
+package stats
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestHistogramMatchesExactPercentiles(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	distributions := map[string]func() time.Duration{
+		"uniform": func() time.Duration {
+			return time.Duration(rng.Int64N(int64(100 * time.Millisecond)))
+		},
+		"exponential": func() time.Duration {
+			return time.Duration(rng.ExpFloat64() * float64(2*time.Millisecond))
+		},
+		"long tail": func() time.Duration {
+			if rng.IntN(100) == 0 {
+				return time.Duration(rng.Int64N(int64(3 * time.Second)))
+			}
+			return 500*time.Microsecond + time.Duration(rng.Int64N(int64(time.Millisecond)))
+		},
+	}
+
+	percentiles := []float64{0, 1, 25, 50, 75, 90, 95, 99, 99.9, 99.99, 100}
+
+	for name, next := range distributions {
+		for precision := MinPrecision; precision <= MaxPrecision; precision++ {
+			h := NewHistogram(precision)
+			exact := &exactRecorder{}
+			for range 20000 {
+				d := next()
+				h.Record(d)
+				exact.Record(d)
+			}
+
+			// Documented guarantee: relative error within 1/(2*10^precision),
+			// plus 1ns to account for the truncation of the interpolated value
+			maxRelativeError := 1 / (2 * math.Pow10(precision))
+
+			for _, p := range percentiles {
+				want := exact.Percentile(p)
+				got := h.Percentile(p)
+				tolerance := time.Duration(float64(want)*maxRelativeError) + 1
+				if diff := got - want; diff > tolerance || diff < -tolerance {
+					t.Errorf("%s, precision %d: P%v = %v, exact %v (tolerance %v)",
+						name, precision, p, got, want, tolerance)
+				}
+			}
+
+			if h.Min() != exact.Min() || h.Max() != exact.Max() || h.Mean() != exact.Mean() {
+				t.Errorf("%s, precision %d: min/max/mean %v/%v/%v, exact %v/%v/%v", name, precision,
+					h.Min(), h.Max(), h.Mean(), exact.Min(), exact.Max(), exact.Mean())
+			}
+		}
+	}
+}
+
+func TestHistogramBoundedMemory(t *testing.T) {
+	h := NewHistogram(DefaultPrecision)
+
+	for i := range 1_000_000 {
+		h.Record(time.Duration(i%10000) * time.Microsecond)
+	}
+
+	if h.Count() != 1_000_000 {
+		t.Errorf("Expected 1000000 values, got %d", h.Count())
+	}
+
+	// 0 to 10s spans 34 power of two ranges of 1024 sub-buckets each
+	if len(h.counts) > 34*1024 {
+		t.Errorf("Expected at most %d buckets, got %d", 34*1024, len(h.counts))
+	}
+}
+
+func TestHistogramBucketRoundTrip(t *testing.T) {
+	h := NewHistogram(2)
+	h.min, h.max = 0, math.MaxInt64
+
+	values := []int64{0, 1, 255, 256, 257, 1000, 123456, int64(time.Second), math.MaxInt64 / 2}
+	for _, v := range values {
+		index := h.bucketIndex(v)
+		represented := int64(h.bucketValue(index))
+		if math.Abs(float64(represented-v)) > float64(v)/200+1 {
+			t.Errorf("Value %d is represented by %d, beyond the histogram precision", v, represented)
+		}
+	}
+
+	// Indexes must be monotonic so that ranks are ordered
+	indexes := make([]int, 0, len(values))
+	for _, v := range values {
+		indexes = append(indexes, h.bucketIndex(v))
+	}
+	if !slices.IsSorted(indexes) {
+		t.Errorf("Bucket indexes are not monotonic: %v", indexes)
+	}
+}
+
+func TestHistogramEmpty(t *testing.T) {
+	h := NewHistogram(DefaultPrecision)
+
+	if h.Count() != 0 || h.Percentile(99) != 0 || h.Mean() != 0 {
+		t.Errorf("Expected zero values for an empty histogram")
+	}
+}
+
+func TestNewHistogramInvalidPrecision(t *testing.T) {
+	for _, precision := range []int{0, 6} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewHistogram(%d) should panic", precision)
+				}
+			}()
+			NewHistogram(precision)
+		}()
+	}
+}
+
+func TestStatisticsWithHistogram(t *testing.T) {
+	s := NewWithPrecision(DefaultPrecision)
+
+	for i := 1; i <= 100; i++ {
+		s.Add(Sample{Hostname: "host_000001", Duration: time.Duration(i) * time.Millisecond})
+	}
+
+	s.Compute()
+
+	if s.MinTime != time.Millisecond || s.MaxTime != 100*time.Millisecond {
+		t.Errorf("Expected exact min 1ms and max 100ms, got %v and %v", s.MinTime, s.MaxTime)
+	}
+	if s.AvgTime != 50500*time.Microsecond {
+		t.Errorf("Expected exact average 50.5ms, got %v", s.AvgTime)
+	}
+	if p99 := s.Percentiles[99]; p99 < 98*time.Millisecond || p99 > 100*time.Millisecond {
+		t.Errorf("Expected P99 to be around 99ms, got %v", p99)
+	}
+	if len(s.Hosts) != 1 || s.Hosts[0].P95 < 94*time.Millisecond || s.Hosts[0].P95 > 96*time.Millisecond {
+		t.Errorf("Expected host P95 to be around 95ms, got %+v", s.Hosts)
+	}
+}
@@ -0,0 +1,388 @@
+package stats
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// SchemaVersion is the version of the JSON report written by WriteJSON.
+// It is incremented whenever a field is removed or changes meaning; new fields
+// may be added without a version change.
+const SchemaVersion = 2
+
+// jsonReport is the top level JSON report document.
+// Durations are expressed in nanoseconds and timestamps in RFC 3339 format.
+type jsonReport struct {
+	SchemaVersion int               `json:"schema_version"`
+	Run           jsonRun           `json:"run"`
+	Queries       jsonQueries       `json:"queries"`
+	Throughput    jsonThroughput    `json:"throughput"`
+	Latency       *jsonLatency      `json:"latency"`                  // null when there are no successful queries
+	Errors        []jsonErrorClass  `json:"errors"`                   // sorted from the most to the least frequent class
+	Phases        *jsonPhases       `json:"phases,omitempty"`         // only present when the phases were measured
+	FailedLatency *jsonSummary      `json:"failed_latency,omitempty"` // only present when queries failed
+	Verification  *jsonVerification `json:"verification,omitempty"`   // only present when the results are verified
+	Warmup        *jsonSummary      `json:"warmup,omitempty"`         // only present when there were warm-up queries
+	Hosts         []jsonHost        `json:"hosts"`                    // sorted from the slowest to the fastest host
+	Workers       []jsonWorker      `json:"workers"`
+	Imbalance     jsonImbalance     `json:"imbalance"`
+	Templates     []jsonTemplate    `json:"templates"`
+	Stages        []jsonStage       `json:"stages"`
+	Retries       *jsonRetries      `json:"retries,omitempty"`  // only present when the queries are retried
+	Schedule      *jsonSchedule     `json:"schedule,omitempty"` // only present for open-loop runs
+	Explain       *jsonExplain      `json:"explain,omitempty"`  // only present when queries are sampled with EXPLAIN ANALYZE
+	Timeline      jsonTimeline      `json:"timeline"`
+}
+
+type jsonRun struct {
+	Workers            int       `json:"workers"`
+	InputFile          string    `json:"input_file"`
+	Iterations         int       `json:"iterations,omitempty"` // only present when looping over the input
+	QueryTimeoutNs     int64     `json:"query_timeout_ns,omitempty"`
+	StatementTimeoutNs int64     `json:"statement_timeout_ns,omitempty"` // only present when set on the server
+	StartTime          time.Time `json:"start_time"`
+	EndTime            time.Time `json:"end_time"`
+	ProcessingTimeNs   int64     `json:"processing_time_ns"`
+}
+
+type jsonQueries struct {
+	Total      int   `json:"total"`
+	Successful int   `json:"successful"`
+	Failed     int   `json:"failed"`
+	TimedOut   int   `json:"timed_out"` // included in failed
+	Rows       int64 `json:"rows"`      // returned by the successful queries
+}
+
+// jsonThroughput excludes the warm-up phase
+type jsonThroughput struct {
+	QueriesPerSecond float64 `json:"queries_per_second"`
+	RowsPerSecond    float64 `json:"rows_per_second"`
+}
+
+type jsonLatency struct {
+	MinNs       int64            `json:"min_ns"`
+	AvgNs       int64            `json:"avg_ns"`
+	MedianNs    int64            `json:"median_ns"`
+	MaxNs       int64            `json:"max_ns"`
+	Percentiles []jsonPercentile `json:"percentiles"` // in ascending order
+}
+
+type jsonPercentile struct {
+	Percentile float64 `json:"percentile"`
+	ValueNs    int64   `json:"value_ns"`
+}
+
+// jsonSummary holds the fields shared by the host, worker and template breakdowns
+type jsonSummary struct {
+	Queries  int   `json:"queries"`
+	Errors   int   `json:"errors"`
+	MinNs    int64 `json:"min_ns"`
+	MedianNs int64 `json:"median_ns"`
+	MaxNs    int64 `json:"max_ns"`
+	P95Ns    int64 `json:"p95_ns"`
+	P99Ns    int64 `json:"p99_ns"`
+}
+
+type jsonPhases struct {
+	FirstRow   jsonSummary      `json:"first_row"`
+	Fetch      jsonSummary      `json:"fetch"`
+	Total      jsonSummary      `json:"total"`
+	Rows       jsonDistribution `json:"rows"`  // per query
+	Bytes      jsonDistribution `json:"bytes"` // per query
+	TotalBytes int64            `json:"total_bytes"`
+}
+
+type jsonVerification struct {
+	Method     string                 `json:"method"`
+	Checked    int                    `json:"checked"`
+	Mismatches int                    `json:"mismatches"`
+	Unknown    int                    `json:"unknown"`
+	Hosts      []jsonHostVerification `json:"hosts"` // sorted from the most to the least mismatches
+}
+
+type jsonHostVerification struct {
+	Hostname   string `json:"hostname"`
+	Checked    int    `json:"checked"`
+	Mismatches int    `json:"mismatches"`
+	Unknown    int    `json:"unknown"`
+	Sample     string `json:"sample,omitempty"`
+}
+
+type jsonErrorClass struct {
+	Class  string `json:"class"`
+	Count  int    `json:"count"`
+	Sample string `json:"sample"`
+}
+
+type jsonHost struct {
+	Hostname string `json:"hostname"`
+	jsonSummary
+}
+
+type jsonWorker struct {
+	ID     int   `json:"id"`
+	BusyNs int64 `json:"busy_ns"`
+	IdleNs int64 `json:"idle_ns"`
+	jsonSummary
+}
+
+type jsonTemplate struct {
+	Name string `json:"name"`
+	jsonSummary
+}
+
+type jsonStage struct {
+	Index      int     `json:"index"`
+	Workers    int     `json:"workers"`
+	DurationNs int64   `json:"duration_ns"`
+	Throughput float64 `json:"queries_per_second"`
+	jsonSummary
+}
+
+type jsonRetries struct {
+	MaxAttempts    int         `json:"max_attempts"`
+	RetriedQueries int         `json:"retried_queries"`
+	Retries        int         `json:"retries"`
+	Recovered      int         `json:"recovered"`
+	FirstAttempt   jsonSummary `json:"first_attempt"`
+	EndToEnd       jsonSummary `json:"end_to_end"`
+}
+
+type jsonSchedule struct {
+	TargetRate  float64 `json:"target_rate"`
+	ReplaySpeed float64 `json:"replay_speed"`
+	Records     int     `json:"records"`
+	MedianLagNs int64   `json:"median_lag_ns"`
+	P99LagNs    int64   `json:"p99_lag_ns"`
+	MaxLagNs    int64   `json:"max_lag_ns"`
+}
+
+type jsonExplain struct {
+	Rate             float64          `json:"rate"`
+	Client           jsonSummary      `json:"client"`
+	PlanningTime     jsonSummary      `json:"planning_time"`
+	ExecutionTime    jsonSummary      `json:"execution_time"`
+	Chunks           jsonDistribution `json:"chunks"`
+	SharedHitBlocks  jsonDistribution `json:"shared_hit_blocks"`
+	SharedReadBlocks jsonDistribution `json:"shared_read_blocks"`
+}
+
+type jsonDistribution struct {
+	Median int64 `json:"median"`
+	P95    int64 `json:"p95"`
+	P99    int64 `json:"p99"`
+	Max    int64 `json:"max"`
+}
+
+type jsonTimeline struct {
+	IntervalNs int64          `json:"interval_ns"`
+	Intervals  []jsonInterval `json:"intervals"` // in chronological order
+}
+
+type jsonInterval struct {
+	StartNs    int64   `json:"start_ns"` // offset from the start of the run
+	DurationNs int64   `json:"duration_ns"`
+	Throughput float64 `json:"queries_per_second"`
+	Rows       int64   `json:"rows"`
+	jsonSummary
+}
+
+type jsonImbalance struct {
+	MaxQueries             int     `json:"max_queries"`
+	MinQueries             int     `json:"min_queries"`
+	CoefficientOfVariation float64 `json:"coefficient_of_variation"`
+}
+
+// WriteJSON writes the statistics as a JSON document to the provided output.
+// Compute must be called before WriteJSON.
+func (s *Statistics) WriteJSON(out io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := jsonReport{
+		SchemaVersion: SchemaVersion,
+		Run: jsonRun{
+			Workers:            s.Metadata.Workers,
+			InputFile:          s.Metadata.InputFile,
+			Iterations:         s.Metadata.Iterations,
+			QueryTimeoutNs:     s.Metadata.QueryTimeout.Nanoseconds(),
+			StatementTimeoutNs: s.Metadata.StatementTimeout.Nanoseconds(),
+			StartTime:          s.Metadata.StartTime,
+			EndTime:            s.Metadata.EndTime,
+			ProcessingTimeNs:   s.ProcessingTime.Nanoseconds(),
+		},
+		Queries: jsonQueries{
+			Total:      s.TotalQueries,
+			Successful: s.TotalQueries - s.FailedQueries,
+			Failed:     s.FailedQueries,
+			TimedOut:   s.TimedOutQueries,
+			Rows:       s.TotalRows,
+		},
+		Throughput: jsonThroughput{
+			QueriesPerSecond: s.QPS,
+			RowsPerSecond:    s.RowsPerSecond,
+		},
+		Errors:    make([]jsonErrorClass, 0, len(s.Errors)),
+		Hosts:     make([]jsonHost, 0, len(s.Hosts)),
+		Workers:   make([]jsonWorker, 0, len(s.Workers)),
+		Templates: make([]jsonTemplate, 0, len(s.Templates)),
+		Stages:    make([]jsonStage, 0, len(s.Stages)),
+		Timeline: jsonTimeline{
+			IntervalNs: s.Interval.Nanoseconds(),
+			Intervals:  make([]jsonInterval, 0, len(s.Timeline)),
+		},
+		Imbalance: jsonImbalance{
+			MaxQueries:             s.Imbalance.MaxQueries,
+			MinQueries:             s.Imbalance.MinQueries,
+			CoefficientOfVariation: s.Imbalance.CoefficientOfVariation,
+		},
+	}
+
+	if s.latencies.Count() > 0 {
+		report.Latency = &jsonLatency{
+			MinNs:       s.MinTime.Nanoseconds(),
+			AvgNs:       s.AvgTime.Nanoseconds(),
+			MedianNs:    s.MedianTime.Nanoseconds(),
+			MaxNs:       s.MaxTime.Nanoseconds(),
+			Percentiles: make([]jsonPercentile, 0, len(s.ReportedPercentiles)),
+		}
+		for _, p := range s.ReportedPercentiles {
+			report.Latency.Percentiles = append(report.Latency.Percentiles, jsonPercentile{
+				Percentile: p,
+				ValueNs:    s.Percentiles[p].Nanoseconds(),
+			})
+		}
+	}
+
+	if s.Phases.Total.Queries > 0 {
+		report.Phases = &jsonPhases{
+			FirstRow:   newJSONSummary(s.Phases.FirstRow),
+			Fetch:      newJSONSummary(s.Phases.Fetch),
+			Total:      newJSONSummary(s.Phases.Total),
+			Rows:       jsonDistribution(s.Phases.Rows),
+			Bytes:      jsonDistribution(s.Phases.Bytes),
+			TotalBytes: s.Phases.TotalBytes,
+		}
+	}
+
+	for _, e := range s.Errors {
+		report.Errors = append(report.Errors, jsonErrorClass{Class: e.Class, Count: e.Count, Sample: e.Sample})
+	}
+	if s.FailedLatency.Queries > 0 {
+		failed := newJSONSummary(s.FailedLatency)
+		report.FailedLatency = &failed
+	}
+
+	if v := s.Verification; v != nil {
+		report.Verification = &jsonVerification{
+			Method:     v.Method,
+			Checked:    v.Checked,
+			Mismatches: v.Mismatches,
+			Unknown:    v.Unknown,
+			Hosts:      make([]jsonHostVerification, 0, len(v.Hosts)),
+		}
+		for _, h := range v.Hosts {
+			report.Verification.Hosts = append(report.Verification.Hosts, jsonHostVerification(h))
+		}
+	}
+
+	if s.Warmup.Queries > 0 {
+		warmup := newJSONSummary(s.Warmup)
+		report.Warmup = &warmup
+	}
+
+	if s.Retries != nil {
+		report.Retries = &jsonRetries{
+			MaxAttempts:    s.Retries.MaxAttempts,
+			RetriedQueries: s.Retries.RetriedQueries,
+			Retries:        s.Retries.Retries,
+			Recovered:      s.Retries.Recovered,
+			FirstAttempt:   newJSONSummary(s.Retries.FirstAttempt),
+			EndToEnd:       newJSONSummary(s.Retries.EndToEnd),
+		}
+	}
+
+	if s.Schedule != nil {
+		report.Schedule = &jsonSchedule{
+			TargetRate:  s.Schedule.TargetRate,
+			ReplaySpeed: s.Schedule.ReplaySpeed,
+			Records:     s.Schedule.Records,
+			MedianLagNs: s.Schedule.MedianLag.Nanoseconds(),
+			P99LagNs:    s.Schedule.P99Lag.Nanoseconds(),
+			MaxLagNs:    s.Schedule.MaxLag.Nanoseconds(),
+		}
+	}
+
+	if s.Explain != nil {
+		report.Explain = &jsonExplain{
+			Rate:             s.Explain.Rate,
+			Client:           newJSONSummary(s.Explain.Client),
+			PlanningTime:     newJSONSummary(s.Explain.PlanningTime),
+			ExecutionTime:    newJSONSummary(s.Explain.ExecutionTime),
+			Chunks:           jsonDistribution(s.Explain.Chunks),
+			SharedHitBlocks:  jsonDistribution(s.Explain.SharedHitBlocks),
+			SharedReadBlocks: jsonDistribution(s.Explain.SharedReadBlocks),
+		}
+	}
+
+	for _, h := range s.Hosts {
+		report.Hosts = append(report.Hosts, jsonHost{
+			Hostname:    h.Hostname,
+			jsonSummary: newJSONSummary(h.Summary),
+		})
+	}
+
+	for _, w := range s.Workers {
+		report.Workers = append(report.Workers, jsonWorker{
+			ID:          w.ID,
+			BusyNs:      w.BusyTime.Nanoseconds(),
+			IdleNs:      w.IdleTime.Nanoseconds(),
+			jsonSummary: newJSONSummary(w.Summary),
+		})
+	}
+
+	for _, t := range s.Templates {
+		report.Templates = append(report.Templates, jsonTemplate{
+			Name:        t.Name,
+			jsonSummary: newJSONSummary(t.Summary),
+		})
+	}
+
+	for _, st := range s.Stages {
+		report.Stages = append(report.Stages, jsonStage{
+			Index:       st.Index,
+			Workers:     st.Workers,
+			DurationNs:  st.Duration.Nanoseconds(),
+			Throughput:  st.Throughput(),
+			jsonSummary: newJSONSummary(st.Summary),
+		})
+	}
+
+	for _, i := range s.Timeline {
+		report.Timeline.Intervals = append(report.Timeline.Intervals, jsonInterval{
+			StartNs:     i.Start.Nanoseconds(),
+			DurationNs:  i.Duration.Nanoseconds(),
+			Throughput:  i.Throughput(),
+			Rows:        i.Rows,
+			jsonSummary: newJSONSummary(i.Summary),
+		})
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// newJSONSummary converts a summary to its JSON representation
+func newJSONSummary(summary Summary) jsonSummary {
+	return jsonSummary{
+		Queries:  summary.Queries,
+		Errors:   summary.Errors,
+		MinNs:    summary.MinTime.Nanoseconds(),
+		MedianNs: summary.MedianTime.Nanoseconds(),
+		MaxNs:    summary.MaxTime.Nanoseconds(),
+		P95Ns:    summary.P95.Nanoseconds(),
+		P99Ns:    summary.P99.Nanoseconds(),
+	}
+}
@@ -0,0 +1,86 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestWriteJSON(t *testing.T) {
+	s := New()
+	s.Metadata = Metadata{
+		Workers:   4,
+		InputFile: "query_params.csv",
+		StartTime: time.Date(2017, 1, 1, 8, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2017, 1, 1, 8, 0, 1, 0, time.UTC),
+	}
+	s.ProcessingTime = time.Second
+
+	for i := 1; i <= 100; i++ {
+		s.Record(time.Duration(i) * time.Millisecond)
+	}
+	s.RecordError()
+	s.Compute()
+
+	var buf bytes.Buffer
+	if err := s.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() failed: %v", err)
+	}
+
+	var report jsonReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("WriteJSON() produced invalid JSON: %v", err)
+	}
+
+	if report.SchemaVersion != SchemaVersion {
+		t.Errorf("Expected schema version %d, got %d", SchemaVersion, report.SchemaVersion)
+	}
+	if report.Run.Workers != 4 || report.Run.InputFile != "query_params.csv" {
+		t.Errorf("Unexpected run metadata: %+v", report.Run)
+	}
+	if !report.Run.StartTime.Equal(s.Metadata.StartTime) || !report.Run.EndTime.Equal(s.Metadata.EndTime) {
+		t.Errorf("Unexpected run timestamps: %+v", report.Run)
+	}
+	if report.Run.ProcessingTimeNs != time.Second.Nanoseconds() {
+		t.Errorf("Expected processing time %d, got %d", time.Second.Nanoseconds(), report.Run.ProcessingTimeNs)
+	}
+	if report.Queries.Total != 101 || report.Queries.Successful != 100 || report.Queries.Failed != 1 {
+		t.Errorf("Unexpected query counts: %+v", report.Queries)
+	}
+	if report.Latency == nil {
+		t.Fatal("Expected latency section, got null")
+	}
+	if report.Latency.MinNs != s.MinTime.Nanoseconds() || report.Latency.MaxNs != s.MaxTime.Nanoseconds() {
+		t.Errorf("Unexpected min/max latency: %+v", report.Latency)
+	}
+	if len(report.Latency.Percentiles) != len(DefaultPercentiles) {
+		t.Fatalf("Expected %d percentiles, got %d", len(DefaultPercentiles), len(report.Latency.Percentiles))
+	}
+	for i, p := range report.Latency.Percentiles {
+		if p.Percentile != DefaultPercentiles[i] || p.ValueNs != s.Percentiles[p.Percentile].Nanoseconds() {
+			t.Errorf("Unexpected percentile %+v, expected P%v = %d",
+				p, DefaultPercentiles[i], s.Percentiles[DefaultPercentiles[i]].Nanoseconds())
+		}
+	}
+}
+
+func TestWriteJSONNoSuccessfulQueries(t *testing.T) {
+	s := New()
+	s.RecordError()
+	s.Compute()
+
+	var buf bytes.Buffer
+	if err := s.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() failed: %v", err)
+	}
+
+	var report map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("WriteJSON() produced invalid JSON: %v", err)
+	}
+
+	if latency, ok := report["latency"]; !ok || latency != nil {
+		t.Errorf("Expected null latency section, got %v", latency)
+	}
+}
@@ -0,0 +1,44 @@
+package stats
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultPercentiles are the percentiles reported when none are configured
+var DefaultPercentiles = []float64{90, 95, 99}
+
+// ParsePercentiles parses a comma separated list of percentiles such as "50,99.9,99.99".
+// The returned list is sorted and free of duplicates.
+func ParsePercentiles(list string) ([]float64, error) {
+	var percentiles []float64
+	for field := range strings.SplitSeq(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		p, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid percentile %q: %w", field, err)
+		}
+		if p < 0 || p > 100 {
+			return nil, fmt.Errorf("invalid percentile %q: must be between 0 and 100", field)
+		}
+		percentiles = append(percentiles, p)
+	}
+
+	if len(percentiles) == 0 {
+		return nil, fmt.Errorf("no percentile provided")
+	}
+
+	slices.Sort(percentiles)
+	return slices.Compact(percentiles), nil
+}
+
+// PercentileLabel returns the label of a percentile as shown in reports, e.g. "P99.9"
+func PercentileLabel(p float64) string {
+	return "P" + strconv.FormatFloat(p, 'f', -1, 64)
+}
@@ -0,0 +1,81 @@
+package stats
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePercentiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		list    string
+		want    []float64
+		wantErr bool
+	}{
+		{name: "default list", list: "90,95,99", want: []float64{90, 95, 99}},
+		{name: "fractional percentiles", list: "50, 99.9,99.99", want: []float64{50, 99.9, 99.99}},
+		{name: "unsorted with duplicates", list: "99,50,99", want: []float64{50, 99}},
+		{name: "bounds", list: "0,100", want: []float64{0, 100}},
+		{name: "empty list", list: "", wantErr: true},
+		{name: "not a number", list: "p99", wantErr: true},
+		{name: "above 100", list: "50,100.1", wantErr: true},
+		{name: "negative", list: "-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePercentiles(tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePercentiles(%q) error = %v, wantErr %v", tt.list, err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParsePercentiles(%q) = %v, want %v", tt.list, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPercentileLabel(t *testing.T) {
+	tests := map[float64]string{
+		50:    "P50",
+		99.9:  "P99.9",
+		99.99: "P99.99",
+	}
+
+	for p, want := range tests {
+		if got := PercentileLabel(p); got != want {
+			t.Errorf("PercentileLabel(%v) = %q, want %q", p, got, want)
+		}
+	}
+}
+
+func TestReportedPercentiles(t *testing.T) {
+	s := New()
+	s.ReportedPercentiles = []float64{50, 99.9, 99.99}
+
+	for i := 1; i <= 10000; i++ {
+		s.Record(time.Duration(i) * time.Microsecond)
+	}
+	s.Compute()
+
+	if len(s.Percentiles) != 3 {
+		t.Fatalf("Expected 3 percentiles, got %v", s.Percentiles)
+	}
+	if p := s.Percentiles[99.9]; p < 9989*time.Microsecond || p > 9991*time.Microsecond {
+		t.Errorf("Expected P99.9 to be around 9.99ms, got %v", p)
+	}
+
+	var buf bytes.Buffer
+	s.Print(&buf)
+	for _, label := range []string{"P50:", "P99.9:", "P99.99:"} {
+		if !strings.Contains(buf.String(), label) {
+			t.Errorf("Expected %s in the report, got:\n%s", label, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "P90:") {
+		t.Errorf("P90 is not configured and should not be reported, got:\n%s", buf.String())
+	}
+}
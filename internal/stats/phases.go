@@ -0,0 +1,83 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// Phases breaks the latency of the successful queries down into the time to the first row,
+// which covers the planning and execution on the server, and the time spent fetching the
+// rest of the rows, which dominates for queries returning many rows
+type Phases struct {
+	FirstRow   Summary // from sending the query to receiving its first row
+	Fetch      Summary // from the first row to the last one
+	Total      Summary // sum of both, leaving out the schedule lag of open-loop runs
+	Rows       Distribution
+	Bytes      Distribution
+	TotalBytes int64 // approximate size of the rows returned by the queries
+
+	// Counts are recorded as durations of one nanosecond per unit
+	rows  recorder
+	bytes recorder
+}
+
+// newPhases creates empty phase statistics
+func (s *Statistics) newPhases() Phases {
+	return Phases{
+		FirstRow: s.newSummary(),
+		Fetch:    s.newSummary(),
+		Total:    s.newSummary(),
+		rows:     s.newBreakdownRecorder(),
+		bytes:    s.newBreakdownRecorder(),
+	}
+}
+
+// recordPhases adds the phases of a successful query, unless they were not measured
+// Must be called with mutex locked
+func (s *Statistics) recordPhases(sample Sample) {
+	if sample.FirstRow <= 0 {
+		return
+	}
+	p := &s.Phases
+	p.FirstRow.record(sample.FirstRow)
+	p.Fetch.record(sample.Fetch)
+	p.Total.record(sample.FirstRow + sample.Fetch)
+	p.rows.Record(time.Duration(sample.Rows))
+	p.bytes.Record(time.Duration(sample.Bytes))
+	p.TotalBytes += sample.Bytes
+}
+
+// computePhases calculates the phase statistics
+// Must be called with mutex locked
+func (s *Statistics) computePhases() {
+	p := &s.Phases
+	p.FirstRow.compute()
+	p.Fetch.compute()
+	p.Total.compute()
+	p.Rows = newDistribution(p.rows)
+	p.Bytes = newDistribution(p.bytes)
+}
+
+// printPhases outputs the time to the first row and the fetch time of the successful queries
+func (s *Statistics) printPhases(out io.Writer) {
+	p := s.Phases
+	_, _ = fmt.Fprintf(out, "\nQuery Phases (%d bytes received):\n", p.TotalBytes)
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "  Phase\tMedian\tP95\tP99\tMaximum")
+	for _, row := range []struct {
+		name    string
+		summary Summary
+	}{
+		{"Time to first row", p.FirstRow},
+		{"Fetch", p.Fetch},
+		{"Total", p.Total},
+	} {
+		_, _ = fmt.Fprintf(tw, "  %s\t%v\t%v\t%v\t%v\n",
+			row.name, row.summary.MedianTime, row.summary.P95, row.summary.P99, row.summary.MaxTime)
+	}
+	_, _ = fmt.Fprintf(tw, "  Rows per query\t%d\t%d\t%d\t%d\n", p.Rows.Median, p.Rows.P95, p.Rows.P99, p.Rows.Max)
+	_, _ = fmt.Fprintf(tw, "  Bytes per query\t%d\t%d\t%d\t%d\n", p.Bytes.Median, p.Bytes.P95, p.Bytes.P99, p.Bytes.Max)
+	_ = tw.Flush()
+}
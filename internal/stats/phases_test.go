@@ -0,0 +1,73 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPhases(t *testing.T) {
+	s := New()
+
+	for i := 1; i <= 4; i++ {
+		s.Add(Sample{Hostname: "host_000001", Duration: time.Duration(i) * 11 * time.Millisecond, Rows: int64(i) * 60,
+			Bytes: int64(i) * 1000, FirstRow: time.Duration(i) * time.Millisecond, Fetch: time.Duration(i) * 10 * time.Millisecond})
+	}
+	// Not measured
+	s.Add(Sample{Hostname: "host_000001", Duration: time.Millisecond})
+	s.Add(Sample{Hostname: "host_000001", Duration: time.Second, FirstRow: time.Second, Err: errors.New("connection reset by peer")})
+	s.Compute()
+
+	p := s.Phases
+	if p.FirstRow.Queries != 4 || p.FirstRow.MaxTime != 4*time.Millisecond {
+		t.Errorf("Unexpected time to first row %+v", p.FirstRow)
+	}
+	if p.Fetch.MaxTime != 40*time.Millisecond || p.Total.MaxTime != 44*time.Millisecond {
+		t.Errorf("Expected a maximum fetch time of 40ms and total of 44ms, got %v and %v", p.Fetch.MaxTime, p.Total.MaxTime)
+	}
+	if p.Rows.Max != 240 || p.Bytes.Max != 4000 || p.TotalBytes != 10000 {
+		t.Errorf("Unexpected rows %+v, bytes %+v and total bytes %d", p.Rows, p.Bytes, p.TotalBytes)
+	}
+
+	var out strings.Builder
+	s.Print(&out)
+	for _, want := range []string{"Query Phases (10000 bytes received):", "Time to first row", "Fetch", "Bytes per query"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in the report:\n%s", want, out.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := s.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() failed: %v", err)
+	}
+	var report struct {
+		Phases *struct {
+			FirstRow struct {
+				MaxNs int64 `json:"max_ns"`
+			} `json:"first_row"`
+			TotalBytes int64 `json:"total_bytes"`
+		} `json:"phases"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if report.Phases == nil || report.Phases.FirstRow.MaxNs != int64(4*time.Millisecond) || report.Phases.TotalBytes != 10000 {
+		t.Errorf("Unexpected phases section %+v", report.Phases)
+	}
+}
+
+func TestPhasesNotMeasured(t *testing.T) {
+	s := New()
+	s.Add(Sample{Hostname: "host_000001", Duration: time.Millisecond})
+	s.Compute()
+
+	var out strings.Builder
+	s.Print(&out)
+	if strings.Contains(out.String(), "Query Phases") {
+		t.Errorf("Expected no phases unless measured:\n%s", out.String())
+	}
+}
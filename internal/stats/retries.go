@@ -0,0 +1,75 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// Retries summarizes the retries of the queries that failed with a transient error.
+// The latency of the first attempt of the successful queries is reported next to their
+// end-to-end latency, which includes the failed attempts and the backoff between them.
+type Retries struct {
+	MaxAttempts    int // attempts allowed per query
+	RetriedQueries int // queries run more than once
+	Retries        int // attempts after the first one
+	Recovered      int // retried queries that eventually succeeded
+	FirstAttempt   Summary
+	EndToEnd       Summary
+}
+
+// SetMaxAttempts enables the retry statistics, the queries being run up to the given number of times
+func (s *Statistics) SetMaxAttempts(attempts int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Retries = &Retries{MaxAttempts: attempts, FirstAttempt: s.newSummary()}
+}
+
+// recordRetries adds the attempts of a query to the retry statistics, unless they are disabled
+// Must be called with mutex locked
+func (s *Statistics) recordRetries(sample Sample) {
+	if s.Retries == nil {
+		return
+	}
+
+	if sample.Attempts > 1 {
+		s.Retries.RetriedQueries++
+		s.Retries.Retries += sample.Attempts - 1
+		if sample.Err == nil {
+			s.Retries.Recovered++
+		}
+	}
+	if sample.Err == nil {
+		s.Retries.FirstAttempt.record(sample.FirstAttempt)
+	}
+}
+
+// computeRetries calculates the latency of the first attempts
+// Must be called with mutex locked
+func (s *Statistics) computeRetries() {
+	if s.Retries == nil {
+		return
+	}
+	s.Retries.FirstAttempt.compute()
+	s.Retries.EndToEnd = Summary{Queries: s.latencies.Count(), latencies: s.latencies}
+	s.Retries.EndToEnd.compute()
+}
+
+// printRetries outputs the retry statistics, comparing the first attempt and end-to-end latencies
+func (s *Statistics) printRetries(out io.Writer) {
+	r := s.Retries
+	_, _ = fmt.Fprintf(out, "\nRetries (up to %d attempts per query):\n", r.MaxAttempts)
+	_, _ = fmt.Fprintf(out, "  Retried queries:  %d (%d retries, %d succeeded)\n", r.RetriedQueries, r.Retries, r.Recovered)
+	if r.EndToEnd.Queries == 0 {
+		return
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "  Latency\tMedian\tP95\tP99\tMaximum")
+	_, _ = fmt.Fprintf(tw, "  First attempt\t%v\t%v\t%v\t%v\n",
+		r.FirstAttempt.MedianTime, r.FirstAttempt.P95, r.FirstAttempt.P99, r.FirstAttempt.MaxTime)
+	_, _ = fmt.Fprintf(tw, "  End-to-end\t%v\t%v\t%v\t%v\n",
+		r.EndToEnd.MedianTime, r.EndToEnd.P95, r.EndToEnd.P99, r.EndToEnd.MaxTime)
+	_ = tw.Flush()
+}
@@ -0,0 +1,78 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetries(t *testing.T) {
+	s := New()
+	s.SetMaxAttempts(3)
+
+	for i := 1; i <= 10; i++ {
+		d := time.Duration(i) * time.Millisecond
+		s.Add(Sample{Hostname: "host_000001", Duration: d, Attempts: 1, FirstAttempt: d})
+	}
+	// Recovered after a connection reset and a backoff
+	s.Add(Sample{Hostname: "host_000001", Duration: 120 * time.Millisecond, Attempts: 2, FirstAttempt: time.Millisecond})
+	// Failed every attempt
+	s.Add(Sample{Hostname: "host_000001", Duration: 300 * time.Millisecond, Attempts: 3, FirstAttempt: time.Millisecond,
+		Err: errors.New("connection reset by peer"), ErrorClass: "network"})
+	// Warm-up queries are left out
+	s.Add(Sample{Hostname: "host_000001", Duration: time.Second, Attempts: 3, FirstAttempt: time.Second, Warmup: true})
+	s.Compute()
+
+	r := s.Retries
+	if r.RetriedQueries != 2 || r.Retries != 3 || r.Recovered != 1 {
+		t.Errorf("Expected 2 retried queries, 3 retries and 1 recovered, got %+v", r)
+	}
+	if r.FirstAttempt.Queries != 11 || r.FirstAttempt.MaxTime != 10*time.Millisecond {
+		t.Errorf("Unexpected first attempt latency %+v", r.FirstAttempt)
+	}
+	if r.EndToEnd.Queries != 11 || r.EndToEnd.MaxTime != 120*time.Millisecond {
+		t.Errorf("Unexpected end-to-end latency %+v", r.EndToEnd)
+	}
+
+	var out strings.Builder
+	s.Print(&out)
+	for _, want := range []string{"Retries (up to 3 attempts per query):", "Retried queries:  2 (3 retries, 1 succeeded)", "First attempt", "End-to-end"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in the report:\n%s", want, out.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := s.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() failed: %v", err)
+	}
+	var report struct {
+		Retries *struct {
+			Retries  int `json:"retries"`
+			EndToEnd struct {
+				MaxNs int64 `json:"max_ns"`
+			} `json:"end_to_end"`
+		} `json:"retries"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid JSON report: %v", err)
+	}
+	if report.Retries == nil || report.Retries.Retries != 3 || report.Retries.EndToEnd.MaxNs != int64(120*time.Millisecond) {
+		t.Errorf("Unexpected retries in the JSON report:\n%s", buf.String())
+	}
+}
+
+func TestRetriesDisabled(t *testing.T) {
+	s := New()
+	s.Add(Sample{Hostname: "host_000001", Duration: time.Millisecond, Attempts: 1})
+	s.Compute()
+
+	var out strings.Builder
+	s.Print(&out)
+	if s.Retries != nil || strings.Contains(out.String(), "Retries") {
+		t.Errorf("Expected no retry statistics without a retry policy:\n%s", out.String())
+	}
+}
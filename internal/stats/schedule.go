@@ -0,0 +1,77 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Schedule summarizes how closely an open-loop run kept to its arrival schedule.
+// The lag of a record is the time between its scheduled send time and the moment a
+// worker started executing it, the queries' latencies being measured from the former.
+type Schedule struct {
+	TargetRate  float64 // records scheduled per second, 0 when replaying the input
+	ReplaySpeed float64 // speed-up factor of the replayed input, 0 for a fixed rate
+	Records     int     // records started
+	MedianLag   time.Duration
+	P99Lag      time.Duration
+	MaxLag      time.Duration
+
+	lags recorder
+}
+
+// SetTargetRate marks the run as open-loop with the given arrival rate in records
+// per second, so that the schedule lag recorded with RecordScheduleLag is reported
+func (s *Statistics) SetTargetRate(rate float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Schedule = &Schedule{TargetRate: rate, lags: newRecorder(s.precision)}
+}
+
+// SetReplaySpeed marks the run as an open-loop replay of the input timing sped up by
+// the given factor, so that the schedule lag recorded with RecordScheduleLag is reported
+func (s *Statistics) SetReplaySpeed(speed float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Schedule = &Schedule{ReplaySpeed: speed, lags: newRecorder(s.precision)}
+}
+
+// RecordScheduleLag adds the lag of a record started behind its scheduled send time.
+// It is ignored unless SetTargetRate or SetReplaySpeed was called.
+func (s *Statistics) RecordScheduleLag(lag time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Schedule == nil {
+		return
+	}
+	s.Schedule.Records++
+	s.Schedule.lags.Record(max(lag, 0))
+}
+
+// computeSchedule calculates the schedule lag statistics
+// Must be called with mutex locked
+func (s *Statistics) computeSchedule() {
+	if s.Schedule == nil || s.Schedule.lags.Count() == 0 {
+		return
+	}
+	s.Schedule.MedianLag = s.Schedule.lags.Percentile(50)
+	s.Schedule.P99Lag = s.Schedule.lags.Percentile(99)
+	s.Schedule.MaxLag = s.Schedule.lags.Max()
+}
+
+// printSchedule outputs the schedule lag of an open-loop run
+func (s *Statistics) printSchedule(out io.Writer) {
+	_, _ = fmt.Fprintf(out, "\nOpen-Loop Schedule (latencies measured from the scheduled send time):\n")
+	if s.Schedule.ReplaySpeed > 0 {
+		_, _ = fmt.Fprintf(out, "  Replay speed:  %gx\n", s.Schedule.ReplaySpeed)
+	} else {
+		_, _ = fmt.Fprintf(out, "  Target rate:   %.1f records/s\n", s.Schedule.TargetRate)
+	}
+	_, _ = fmt.Fprintf(out, "  Records:       %d\n", s.Schedule.Records)
+	_, _ = fmt.Fprintf(out, "  Median lag:    %v\n", s.Schedule.MedianLag)
+	_, _ = fmt.Fprintf(out, "  P99 lag:       %v\n", s.Schedule.P99Lag)
+	_, _ = fmt.Fprintf(out, "  Maximum lag:   %v\n", s.Schedule.MaxLag)
+}
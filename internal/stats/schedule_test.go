@@ -0,0 +1,79 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScheduleLag(t *testing.T) {
+	s := New()
+	s.SetTargetRate(100)
+
+	for i := 1; i <= 100; i++ {
+		s.RecordScheduleLag(time.Duration(i) * time.Millisecond)
+	}
+	s.RecordScheduleLag(-time.Millisecond) // started early, counted as no lag
+	s.Compute()
+
+	if s.Schedule.Records != 101 {
+		t.Errorf("Expected 101 records, got %d", s.Schedule.Records)
+	}
+	if s.Schedule.MedianLag != 50*time.Millisecond {
+		t.Errorf("Expected median lag 50ms, got %v", s.Schedule.MedianLag)
+	}
+	if s.Schedule.MaxLag != 100*time.Millisecond {
+		t.Errorf("Expected max lag 100ms, got %v", s.Schedule.MaxLag)
+	}
+	if s.Schedule.P99Lag < s.Schedule.MedianLag || s.Schedule.P99Lag > s.Schedule.MaxLag {
+		t.Errorf("P99 lag %v out of range", s.Schedule.P99Lag)
+	}
+
+	var out strings.Builder
+	s.Print(&out)
+	if !strings.Contains(out.String(), "Open-Loop Schedule") {
+		t.Errorf("Expected the schedule section in the report:\n%s", out.String())
+	}
+}
+
+func TestScheduleReplay(t *testing.T) {
+	s := New()
+	s.SetReplaySpeed(2.5)
+	s.RecordScheduleLag(time.Millisecond)
+	s.Compute()
+
+	if s.Schedule.ReplaySpeed != 2.5 || s.Schedule.TargetRate != 0 || s.Schedule.Records != 1 {
+		t.Errorf("Unexpected replay schedule %+v", s.Schedule)
+	}
+
+	var out strings.Builder
+	s.Print(&out)
+	if !strings.Contains(out.String(), "Replay speed:  2.5x") {
+		t.Errorf("Expected the replay speed in the report:\n%s", out.String())
+	}
+}
+
+func TestScheduleClosedLoop(t *testing.T) {
+	s := New()
+	s.RecordScheduleLag(time.Millisecond) // ignored without a target rate
+	s.Record(time.Millisecond)
+	s.Compute()
+
+	if s.Schedule != nil {
+		t.Errorf("Expected no schedule for a closed-loop run, got %+v", s.Schedule)
+	}
+
+	var buf bytes.Buffer
+	if err := s.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() failed: %v", err)
+	}
+	var report map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("WriteJSON() produced invalid JSON: %v", err)
+	}
+	if _, ok := report["schedule"]; ok {
+		t.Error("Expected no schedule section in the JSON report")
+	}
+}
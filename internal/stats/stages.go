@@ -0,0 +1,73 @@
+package stats
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+// StageSummary holds the statistics of the queries run during a stage of a load profile
+type StageSummary struct {
+	Index    int           // position of the stage in the profile, from 0
+	Workers  int           // concurrency of the stage
+	Duration time.Duration // time from the start of the stage until its last query completed
+	Summary
+}
+
+// Throughput returns the number of queries completed per second during the stage
+func (st StageSummary) Throughput() float64 {
+	if st.Duration <= 0 {
+		return 0
+	}
+	return float64(st.Queries) / st.Duration.Seconds()
+}
+
+// RecordStage sets the concurrency and the duration of a stage of the load profile
+func (s *Statistics) RecordStage(index, workers int, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stage := s.stage(index)
+	stage.Workers = workers
+	stage.Duration = duration
+}
+
+// stage returns the summary of the given stage, creating it if needed
+// Must be called with mutex locked
+func (s *Statistics) stage(index int) *StageSummary {
+	stage, ok := s.stages[index]
+	if !ok {
+		stage = &StageSummary{Index: index, Summary: s.newSummary()}
+		s.stages[index] = stage
+	}
+	return stage
+}
+
+// computeStages calculates the per-stage statistics
+// Must be called with mutex locked
+func (s *Statistics) computeStages() {
+	s.Stages = make([]StageSummary, 0, len(s.stages))
+	for _, stage := range s.stages {
+		stage.compute()
+		s.Stages = append(s.Stages, *stage)
+	}
+	slices.SortFunc(s.Stages, func(a, b StageSummary) int {
+		return cmp.Compare(a.Index, b.Index)
+	})
+}
+
+// printStages outputs the per-stage statistics
+func (s *Statistics) printStages(out io.Writer) {
+	_, _ = fmt.Fprintln(out, "\nLoad Profile Stages:")
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "  Stage\tWorkers\tDuration\tQueries\tErrors\tQueries/s\tMedian\tP95\tP99")
+	for _, st := range s.Stages {
+		_, _ = fmt.Fprintf(tw, "  %d\t%d\t%v\t%d\t%d\t%.1f\t%v\t%v\t%v\n",
+			st.Index+1, st.Workers, st.Duration.Round(time.Millisecond), st.Queries, st.Errors, st.Throughput(),
+			st.MedianTime, st.P95, st.P99)
+	}
+	_ = tw.Flush()
+}
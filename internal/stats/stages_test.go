@@ -0,0 +1,57 @@
+package stats
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddPerStage(t *testing.T) {
+	s := New()
+
+	for i := 1; i <= 10; i++ {
+		s.Add(Sample{Hostname: "host_000001", Stage: 0, Duration: time.Duration(i) * time.Millisecond})
+		s.Add(Sample{Hostname: "host_000001", Stage: 1, Duration: time.Duration(i) * 10 * time.Millisecond})
+	}
+	s.Add(Sample{Hostname: "host_000001", Stage: 1, Err: errors.New("query failed")})
+	s.RecordStage(1, 4, 2*time.Second)
+	s.RecordStage(0, 2, time.Second)
+	s.Compute()
+
+	if len(s.Stages) != 2 {
+		t.Fatalf("Expected 2 stages, got %d", len(s.Stages))
+	}
+
+	first, second := s.Stages[0], s.Stages[1]
+	if first.Index != 0 || first.Workers != 2 || first.Queries != 10 || first.Errors != 0 {
+		t.Errorf("Unexpected first stage %+v", first)
+	}
+	if second.Index != 1 || second.Workers != 4 || second.Queries != 11 || second.Errors != 1 {
+		t.Errorf("Unexpected second stage %+v", second)
+	}
+	if first.MaxTime != 10*time.Millisecond || second.MaxTime != 100*time.Millisecond {
+		t.Errorf("Unexpected stage maximums %v and %v", first.MaxTime, second.MaxTime)
+	}
+	if got := second.Throughput(); got != 5.5 {
+		t.Errorf("Expected 5.5 queries/s in the second stage, got %v", got)
+	}
+
+	var out strings.Builder
+	s.Print(&out)
+	if !strings.Contains(out.String(), "Load Profile Stages:") {
+		t.Errorf("Expected the stages section in the report:\n%s", out.String())
+	}
+}
+
+func TestSingleStageNotPrinted(t *testing.T) {
+	s := New()
+	s.Add(Sample{Hostname: "host_000001", Duration: time.Millisecond})
+	s.Compute()
+
+	var out strings.Builder
+	s.Print(&out)
+	if strings.Contains(out.String(), "Load Profile Stages:") {
+		t.Errorf("Expected no stages section for a single stage:\n%s", out.String())
+	}
+}
@@ -3,50 +3,309 @@
 //
 // It allows you to record query durations, count errors, and compute
 // summary metrics such as total time, min/max/average durations, median,
-// and percentiles (P90, P95, P99). This package is useful for
-// benchmarking database queries or other time-sensitive operations.
+// and a configurable list of percentiles (P90, P95 and P99 by default). Samples recorded with Add are also
+// broken down per hostname so that hot or skewed hosts can be spotted,
+// per worker together with a load imbalance summary, per load profile stage and per
+// interval of time, the latter forming a timeline of the run. Failed queries are broken
+// down per error class and their latency is reported apart.
+// Warm-up queries are reported on their own, apart from the other statistics. Open-loop runs also
+// report how far behind their arrival schedule the queries were started.
+// This package is useful for benchmarking database queries or other
+// time-sensitive operations.
 //
 // Typical usage:
 //
 //	s := stats.New()
 //	s.Record(duration)
 //	s.RecordError()
+//	s.Add(stats.Sample{Hostname: hostname, Duration: duration, Err: err})
 //	...
 //	s.Compute()
 //	s.Print(os.Stdout)
 //
+// Durations are either all kept in memory to compute exact statistics (New) or
+// recorded in bounded-memory histograms (NewWithPrecision), which is preferable
+// for long runs as memory usage no longer grows with the number of queries.
+//
+// The computed statistics can also be written as a versioned JSON document
+// with WriteJSON for consumption by other tools.
+//
 // The package is safe for concurrent access.
 package stats
 
 import (
+	"cmp"
 	"fmt"
 	"io"
 	"slices"
 	"strings"
 	"sync"
+	"text/tabwriter"
 	"time"
 )
 
+// DefaultTopHosts is the default number of hosts listed in the slowest hosts report section
+const DefaultTopHosts = 10
+
+// DefaultPrecision is the default number of significant digits kept by the latency histograms
+const DefaultPrecision = 3
+
+// breakdownPrecision caps the precision of the per-host and per-worker histograms
+// to bound the memory used when there are many hosts
+const breakdownPrecision = 2
+
+// Metadata describes the benchmark run the statistics belong to
+type Metadata struct {
+	Workers   int
+	InputFile string // empty when the input was read from stdin
+	// Iterations is the number of passes over the input of runs looping over it, 0 otherwise
+	Iterations int
+	// QueryTimeout is the client-side timeout of each query and StatementTimeout the
+	// statement_timeout set on the server sessions, 0 when unknown or not set
+	QueryTimeout     time.Duration
+	StatementTimeout time.Duration
+	StartTime        time.Time
+	EndTime          time.Time
+}
+
 // Statistics holds benchmark statistics
 type Statistics struct {
-	TotalQueries   int
-	ProcessingTime time.Duration
-	MinTime        time.Duration
-	MaxTime        time.Duration
-	MedianTime     time.Duration
-	AvgTime        time.Duration
-	P90            time.Duration // 90th percentile
-	P95            time.Duration // 95th percentile
-	P99            time.Duration // 99th percentile
+	Metadata      Metadata
+	TotalQueries  int
+	FailedQueries int
+	// TimedOutQueries counts the failed queries that ran into the query or statement timeout
+	TimedOutQueries int
+	ProcessingTime  time.Duration
+	MinTime         time.Duration
+	MaxTime         time.Duration
+	MedianTime      time.Duration
+	AvgTime         time.Duration
+	// Percentiles holds the value of each percentile listed in ReportedPercentiles
+	Percentiles map[float64]time.Duration
+	// ReportedPercentiles lists the percentiles computed by Compute, in ascending order
+	ReportedPercentiles []float64
+
+	// Hosts holds the per-hostname statistics sorted from the slowest to the fastest host.
+	// It is populated by Compute from the samples recorded with Add.
+	Hosts []HostSummary
+	// TopHosts is the number of hosts listed by Print in the slowest hosts section
+	TopHosts int
+
+	// TotalRows is the number of rows returned by the successful queries. QPS and
+	// RowsPerSecond are the query and row throughputs over the processing time,
+	// leaving out the warm-up phase. They are populated by Compute.
+	TotalRows     int64
+	QPS           float64
+	RowsPerSecond float64
+
+	// Timeline holds the statistics of every Interval of the run in chronological order,
+	// placing queries by completion time. It is populated by Compute from the samples
+	// recorded with Add, and Interval must be set before adding any.
+	Timeline []IntervalSummary
+	Interval time.Duration
+	// PrintTimeline adds the timeline to the report written by Print
+	PrintTimeline bool
+
+	// Phases breaks the latency of the successful queries down into the time to the first row
+	// and the time spent fetching the rows. It is populated by Compute from the samples
+	// recorded with Add that have a FirstRow.
+	Phases Phases
+
+	// Workers holds the per-worker statistics ordered by worker ID and
+	// Imbalance summarizes how evenly the queries were spread across them.
+	// Both are populated by Compute.
+	Workers   []WorkerSummary
+	Imbalance LoadImbalance
+
+	// Templates holds the statistics of each query template, in order of first appearance.
+	// It is populated by Compute from the samples recorded with Add.
+	Templates []TemplateSummary
+
+	// Errors holds the failed queries per error class, from the most to the least frequent, and
+	// FailedLatency the latency of the failed queries, which is excluded from the other statistics.
+	// Both are populated by Compute from the samples recorded with Add.
+	Errors        []ErrorClassSummary
+	FailedLatency Summary
+
+	// Warmup holds the statistics of the warm-up queries, which are excluded from
+	// every other statistic. It is populated by Compute from the samples recorded with Add.
+	Warmup Summary
+
+	// Stages holds the statistics of each stage of the load profile, in profile order.
+	// It is populated by Compute from the samples recorded with Add and RecordStage.
+	Stages []StageSummary
+
+	// Retries holds the retry statistics and is nil when the queries are not retried.
+	// It is created by SetMaxAttempts and populated by Compute.
+	Retries *Retries
+
+	// Schedule holds the schedule lag of open-loop runs and is nil for closed-loop runs.
+	// It is created by SetTargetRate and populated by Compute.
+	Schedule *Schedule
+
+	// Verification holds the verification of the query results, warm-up included, and is
+	// nil when they are not verified. It is created by SetVerification and populated by Compute.
+	Verification *Verification
+
+	// Explain holds the server-side statistics of the queries sampled with EXPLAIN ANALYZE,
+	// which are excluded from every other statistic, and is nil when no query is sampled.
+	// It is created by SetExplainRate and populated by Compute.
+	Explain *Explain
+
+	precision    int
+	latencies    recorder
+	hosts        map[string]*Summary
+	workers      map[int]*WorkerSummary
+	templates    []*TemplateSummary
+	stages       map[int]*StageSummary
+	intervals    []*IntervalSummary
+	errorClasses map[string]*ErrorClassSummary
+	failed       Summary
+	warmup       Summary
+	warmupEnd    time.Time // completion time of the last warm-up query
+	mu           sync.Mutex
+}
 
-	durations []time.Duration
-	mu        sync.Mutex
+// Sample is the outcome of a single query execution
+type Sample struct {
+	Hostname string
+	Template string // name of the query template
+	WorkerID int
+	Stage    int       // index of the load profile stage
+	End      time.Time // completion time of the query, which places it in the timeline
+	Duration time.Duration
+	Rows     int64 // rows returned by a successful query
+	Bytes    int64 // approximate size of the rows returned by a successful query
+	// FirstRow is the time to the first row of a successful query and Fetch the time spent
+	// fetching the rows after it, 0 when not measured
+	FirstRow time.Duration
+	Fetch    time.Duration
+	Err      error
+	// ErrorClass is the class of Err used to break the errors down, e.g. "deadline_exceeded"
+	ErrorClass string
+	// TimedOut marks the failed queries that ran into a timeout
+	TimedOut bool
+	// Attempts is the number of times the query was run, more than 1 when it was retried,
+	// and FirstAttempt the latency of its first attempt
+	Attempts     int
+	FirstAttempt time.Duration
+	// Warmup marks the queries of the warm-up phase, recorded apart from the other statistics
+	Warmup bool
+	// Explained marks the queries run with EXPLAIN ANALYZE, recorded apart from the other
+	// statistics, and Plan holds their server-side statistics when they succeeded
+	Explained bool
+	Plan      Plan
+	// Verdict is the outcome of the verification of a successful query result and
+	// VerdictDetail describes a mismatch or why the expected result is unknown
+	Verdict       Verdict
+	VerdictDetail string
 }
 
-// New creates a new Statistics instance
+// New creates a new Statistics instance that stores every duration to compute exact statistics
 func New() *Statistics {
-	return &Statistics{
-		durations: make([]time.Duration, 0),
+	return NewWithPrecision(0)
+}
+
+// NewWithPrecision creates a new Statistics instance recording durations in histograms
+// keeping the given number of significant digits, which bounds the memory used regardless
+// of the number of queries. A precision of 0 stores every duration to compute exact statistics.
+// The per-host and per-worker statistics keep at most 2 significant digits.
+// It panics if precision is neither 0 nor between MinPrecision and MaxPrecision.
+func NewWithPrecision(precision int) *Statistics {
+	s := &Statistics{
+		TopHosts:            DefaultTopHosts,
+		Interval:            DefaultInterval,
+		ReportedPercentiles: DefaultPercentiles,
+		precision:           precision,
+		latencies:           newRecorder(precision),
+		hosts:               make(map[string]*Summary),
+		workers:             make(map[int]*WorkerSummary),
+		stages:              make(map[int]*StageSummary),
+		errorClasses:        make(map[string]*ErrorClassSummary),
+	}
+	s.failed = s.newSummary()
+	s.warmup = s.newSummary()
+	s.Phases = s.newPhases()
+	return s
+}
+
+// newSummary creates an empty summary for a breakdown of the statistics
+func (s *Statistics) newSummary() Summary {
+	return Summary{latencies: s.newBreakdownRecorder()}
+}
+
+// newBreakdownRecorder creates an empty recorder for a breakdown of the statistics,
+// keeping at most breakdownPrecision significant digits
+func (s *Statistics) newBreakdownRecorder() recorder {
+	return newRecorder(min(s.precision, breakdownPrecision))
+}
+
+// Add records the outcome of a query in the global, per-host, per-worker, per-template and per-stage statistics
+// unless the sample is a query run with EXPLAIN ANALYZE, which is only recorded in the explain
+// statistics and dropped during the warm-up, or a warm-up query, only recorded in the warm-up statistics.
+// The verdict of the verification of its result is recorded in every case.
+func (s *Statistics) Add(sample Sample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.recordVerification(sample)
+	if sample.Explained {
+		// The plans of the warm-up queries suffer from the same cold caches as their latency
+		if s.Explain != nil && !sample.Warmup {
+			s.recordExplain(sample)
+		}
+		return
+	}
+	if sample.Warmup {
+		if sample.End.After(s.warmupEnd) {
+			s.warmupEnd = sample.End
+		}
+		if sample.Err != nil {
+			s.warmup.recordError()
+		} else {
+			s.warmup.record(sample.Duration)
+		}
+		return
+	}
+
+	host, ok := s.hosts[sample.Hostname]
+	if !ok {
+		summary := s.newSummary()
+		host = &summary
+		s.hosts[sample.Hostname] = host
+	}
+	worker := s.worker(sample.WorkerID)
+	template := s.template(sample.Template)
+	stage := s.stage(sample.Stage)
+	interval := s.interval(sample.End)
+
+	s.TotalQueries++
+	s.recordRetries(sample)
+	if sample.Err != nil {
+		s.FailedQueries++
+		if sample.TimedOut {
+			s.TimedOutQueries++
+		}
+		s.recordFailure(sample)
+		host.recordError()
+		worker.recordError()
+		template.recordError()
+		stage.recordError()
+		if interval != nil {
+			interval.recordError()
+		}
+		return
+	}
+	s.TotalRows += sample.Rows
+	s.recordPhases(sample)
+	s.latencies.Record(sample.Duration)
+	host.record(sample.Duration)
+	worker.record(sample.Duration)
+	template.record(sample.Duration)
+	stage.record(sample.Duration)
+	if interval != nil {
+		interval.record(sample.Duration)
+		interval.Rows += sample.Rows
 	}
 }
 
@@ -56,15 +315,21 @@ func (s *Statistics) Record(duration time.Duration) {
 	defer s.mu.Unlock()
 
 	s.TotalQueries++
-	s.durations = append(s.durations, duration)
+	s.latencies.Record(duration)
 }
 
-// RecordError increments the total query count for a failed query
+// RecordError increments the total and failed query counts for a failed query
 func (s *Statistics) RecordError() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	s.TotalQueries++
+	s.FailedQueries++
+}
+
+// SuccessfulQueries returns the number of queries that completed without error
+func (s *Statistics) SuccessfulQueries() int {
+	return s.TotalQueries - s.FailedQueries
 }
 
 // Compute calculates the final statistics
@@ -72,60 +337,68 @@ func (s *Statistics) Compute() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if len(s.durations) == 0 {
-		return
-	}
-
-	// Sort durations for median and percentile calculations
-	slices.Sort(s.durations)
+	s.computeHosts()
+	s.computeWorkers()
+	s.computeTemplates()
+	s.computeStages()
+	s.computeSchedule()
+	s.computeErrors()
+	s.computeRetries()
+	s.computePhases()
+	s.computeExplain()
+	s.computeVerification()
+	s.computeThroughput()
+	s.computeTimeline()
+	s.warmup.compute()
+	s.Warmup = s.warmup
 
-	// Min and Max
-	s.MinTime = s.durations[0]
-	s.MaxTime = s.durations[len(s.durations)-1]
-
-	// Median
-	mid := len(s.durations) / 2
-	if len(s.durations)%2 == 0 {
-		s.MedianTime = (s.durations[mid-1] + s.durations[mid]) / 2
-	} else {
-		s.MedianTime = s.durations[mid]
+	if s.latencies.Count() == 0 {
+		return
 	}
 
-	// Average
-	var total time.Duration
-	for _, d := range s.durations {
-		total += d
-	}
-	s.AvgTime = total / time.Duration(len(s.durations))
+	s.MinTime = s.latencies.Min()
+	s.MaxTime = s.latencies.Max()
+	s.MedianTime = s.latencies.Percentile(50)
+	s.AvgTime = s.latencies.Mean()
 
 	// Percentiles
-	s.P90 = s.percentile(90)
-	s.P95 = s.percentile(95)
-	s.P99 = s.percentile(99)
+	s.Percentiles = make(map[float64]time.Duration, len(s.ReportedPercentiles))
+	for _, p := range s.ReportedPercentiles {
+		s.Percentiles[p] = s.latencies.Percentile(p)
+	}
 }
 
-// percentile calculates the given percentile from sorted durations
-// Must be called with mutex locked and after durations are sorted
-func (s *Statistics) percentile(p float64) time.Duration {
-	if len(s.durations) == 0 {
+// Percentile returns the latency at the given percentile of the successful queries, 0 if there is none.
+// Unlike Percentiles, it is not limited to ReportedPercentiles.
+func (s *Statistics) Percentile(p float64) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.latencies.Count() == 0 {
 		return 0
 	}
+	return s.latencies.Percentile(p)
+}
 
-	// Use linear interpolation method
-	n := float64(len(s.durations))
-	rank := (p / 100.0) * (n - 1)
-	lower := int(rank)
-	upper := lower + 1
-
-	// Handle edge cases
-	if upper >= len(s.durations) {
-		return s.durations[len(s.durations)-1]
+// computeHosts calculates the per-host statistics and sorts the hosts from the slowest to the fastest
+// Must be called with mutex locked
+func (s *Statistics) computeHosts() {
+	s.Hosts = make([]HostSummary, 0, len(s.hosts))
+	for hostname, summary := range s.hosts {
+		summary.compute()
+		s.Hosts = append(s.Hosts, HostSummary{Hostname: hostname, Summary: *summary})
 	}
 
-	// Linear interpolation between the two nearest values
-	fraction := rank - float64(lower)
-	return time.Duration(float64(s.durations[lower]) +
-		fraction*float64(s.durations[upper]-s.durations[lower]))
+	// Slowest hosts first, ties are broken by median time and then by hostname for a stable order
+	slices.SortFunc(s.Hosts, func(a, b HostSummary) int {
+		if a.P99 != b.P99 {
+			return cmp.Compare(b.P99, a.P99)
+		}
+		if a.MedianTime != b.MedianTime {
+			return cmp.Compare(b.MedianTime, a.MedianTime)
+		}
+		return strings.Compare(a.Hostname, b.Hostname)
+	})
 }
 
 // Print outputs the statistics to the provided output
@@ -135,11 +408,21 @@ func (s *Statistics) Print(out io.Writer) {
 	_, _ = fmt.Fprintln(out, strings.Repeat("=", 60))
 	_, _ = fmt.Fprintf(out, "Number of queries processed: %d\n", s.TotalQueries)
 	_, _ = fmt.Fprintf(out, "Total processing time:       %v\n", s.ProcessingTime)
+	if s.Metadata.Iterations > 0 {
+		_, _ = fmt.Fprintf(out, "Passes over the input:       %d\n", s.Metadata.Iterations)
+	}
+	if s.QPS > 0 {
+		_, _ = fmt.Fprintf(out, "Throughput:                  %.1f queries/s, %.1f rows/s\n", s.QPS, s.RowsPerSecond)
+	}
+
+	if s.Metadata.QueryTimeout > 0 {
+		_, _ = fmt.Fprintf(out, "Timed out queries:           %d (%s)\n", s.TimedOutQueries, s.timeouts())
+	}
 
-	if len(s.durations) > 0 {
+	if successful := s.latencies.Count(); successful > 0 {
 		_, _ = fmt.Fprintf(out, "Successful queries:          %d/%d (%.1f%%)\n\n",
-			len(s.durations), s.TotalQueries,
-			float64(len(s.durations))/float64(s.TotalQueries)*100)
+			successful, s.TotalQueries,
+			float64(successful)/float64(s.TotalQueries)*100)
 
 		_, _ = fmt.Fprintln(out, "Query Time Statistics:")
 		_, _ = fmt.Fprintf(out, "  Minimum:     %v\n", s.MinTime)
@@ -148,11 +431,76 @@ func (s *Statistics) Print(out io.Writer) {
 		_, _ = fmt.Fprintf(out, "  Maximum:     %v\n\n", s.MaxTime)
 
 		_, _ = fmt.Fprintln(out, "Percentiles:")
-		_, _ = fmt.Fprintf(out, "  P90:          %v\n", s.P90)
-		_, _ = fmt.Fprintf(out, "  P95:          %v\n", s.P95)
-		_, _ = fmt.Fprintf(out, "  P99:          %v\n", s.P99)
+		for _, p := range s.ReportedPercentiles {
+			_, _ = fmt.Fprintf(out, "  %-14s%v\n", PercentileLabel(p)+":", s.Percentiles[p])
+		}
 	} else {
 		_, _ = fmt.Fprintln(out, "No successful queries to report timing statistics")
 	}
+
+	if s.Phases.Total.Queries > 0 {
+		s.printPhases(out)
+	}
+	if len(s.Errors) > 0 {
+		s.printErrors(out)
+	}
+	if s.Verification != nil {
+		s.printVerification(out)
+	}
+	if s.Retries != nil {
+		s.printRetries(out)
+	}
+	if s.Explain != nil {
+		s.printExplain(out)
+	}
+	if s.Warmup.Queries > 0 {
+		s.printWarmup(out)
+	}
+	if s.Schedule != nil {
+		s.printSchedule(out)
+	}
+
+	if s.PrintTimeline && len(s.Timeline) > 0 {
+		s.printTimeline(out)
+	}
+
+	// Stages are only broken down when the run followed a load profile
+	if len(s.Stages) > 1 {
+		s.printStages(out)
+	}
+
+	// Templates are only broken down when several queries are benchmarked
+	if len(s.Templates) > 1 {
+		s.printTemplates(out)
+	}
+	if len(s.Hosts) > 0 && s.TopHosts > 0 {
+		s.printHosts(out)
+	}
+	if len(s.Workers) > 0 {
+		s.printWorkers(out)
+	}
 	_, _ = fmt.Fprintln(out, strings.Repeat("=", 60))
 }
+
+// timeouts describes the query and statement timeouts of the run
+func (s *Statistics) timeouts() string {
+	timeouts := fmt.Sprintf("query timeout %v", s.Metadata.QueryTimeout)
+	if s.Metadata.StatementTimeout > 0 {
+		timeouts += fmt.Sprintf(", statement_timeout %v", s.Metadata.StatementTimeout)
+	}
+	return timeouts
+}
+
+// printHosts outputs the slowest hosts ranked by P99
+func (s *Statistics) printHosts(out io.Writer) {
+	hosts := s.Hosts[:min(s.TopHosts, len(s.Hosts))]
+
+	_, _ = fmt.Fprintf(out, "\nSlowest Hosts (by P99, top %d of %d):\n", len(hosts), len(s.Hosts))
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "  Hostname\tQueries\tErrors\tMinimum\tMedian\tMaximum\tP95\tP99")
+	for _, h := range hosts {
+		_, _ = fmt.Fprintf(tw, "  %s\t%d\t%d\t%v\t%v\t%v\t%v\t%v\n",
+			h.Hostname, h.Queries, h.Errors, h.MinTime, h.MedianTime, h.MaxTime, h.P95, h.P99)
+	}
+	_ = tw.Flush()
+}
@@ -1,6 +1,10 @@
 package stats
 
 import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -21,8 +25,8 @@ func TestRecord(t *testing.T) {
 	if s.TotalQueries != 3 {
 		t.Errorf("Expected TotalQueries to be 3, got %d", s.TotalQueries)
 	}
-	if len(s.durations) != 3 {
-		t.Errorf("Expected 3 durations, got %d", len(s.durations))
+	if s.latencies.Count() != 3 {
+		t.Errorf("Expected 3 durations, got %d", s.latencies.Count())
 	}
 }
 
@@ -36,8 +40,8 @@ func TestRecordError(t *testing.T) {
 	if s.TotalQueries != 3 {
 		t.Errorf("Expected TotalQueries to be 3, got %d", s.TotalQueries)
 	}
-	if len(s.durations) != 1 {
-		t.Errorf("Expected 1 duration (errors don't add durations), got %d", len(s.durations))
+	if s.latencies.Count() != 1 {
+		t.Errorf("Expected 1 duration (errors don't add durations), got %d", s.latencies.Count())
 	}
 }
 
@@ -114,18 +118,18 @@ func TestPercentiles(t *testing.T) {
 	s.Compute()
 
 	// P90 should be around 90ms
-	if s.P90 < 89*time.Millisecond || s.P90 > 91*time.Millisecond {
-		t.Errorf("Expected P90 to be around 90ms, got %v", s.P90)
+	if p90 := s.Percentiles[90]; p90 < 89*time.Millisecond || p90 > 91*time.Millisecond {
+		t.Errorf("Expected P90 to be around 90ms, got %v", p90)
 	}
 
 	// P95 should be around 95ms
-	if s.P95 < 94*time.Millisecond || s.P95 > 96*time.Millisecond {
-		t.Errorf("Expected P95 to be around 95ms, got %v", s.P95)
+	if p95 := s.Percentiles[95]; p95 < 94*time.Millisecond || p95 > 96*time.Millisecond {
+		t.Errorf("Expected P95 to be around 95ms, got %v", p95)
 	}
 
 	// P99 should be around 99ms
-	if s.P99 < 98*time.Millisecond || s.P99 > 100*time.Millisecond {
-		t.Errorf("Expected P99 to be around 99ms, got %v", s.P99)
+	if p99 := s.Percentiles[99]; p99 < 98*time.Millisecond || p99 > 100*time.Millisecond {
+		t.Errorf("Expected P99 to be around 99ms, got %v", p99)
 	}
 }
 
@@ -160,7 +164,106 @@ func TestConcurrentRecords(t *testing.T) {
 	if s.TotalQueries != 1000 {
 		t.Errorf("Expected 1000 queries, got %d", s.TotalQueries)
 	}
-	if len(s.durations) != 1000 {
-		t.Errorf("Expected 1000 durations, got %d", len(s.durations))
+	if s.latencies.Count() != 1000 {
+		t.Errorf("Expected 1000 durations, got %d", s.latencies.Count())
+	}
+}
+
+func TestAddPerHost(t *testing.T) {
+	s := New()
+
+	s.Add(Sample{Hostname: "host_000001", Duration: 10 * time.Millisecond})
+	s.Add(Sample{Hostname: "host_000001", Duration: 30 * time.Millisecond})
+	s.Add(Sample{Hostname: "host_000001", Err: errors.New("query failed")})
+	s.Add(Sample{Hostname: "host_000002", Duration: 20 * time.Millisecond})
+
+	s.Compute()
+
+	if s.TotalQueries != 4 || s.FailedQueries != 1 {
+		t.Errorf("Expected 4 queries with 1 failure, got %d with %d failures", s.TotalQueries, s.FailedQueries)
+	}
+	if len(s.Hosts) != 2 {
+		t.Fatalf("Expected 2 hosts, got %d", len(s.Hosts))
+	}
+
+	host := s.Hosts[0]
+	if host.Hostname != "host_000001" {
+		t.Fatalf("Expected host_000001 to be the slowest host, got %q", host.Hostname)
+	}
+	if host.Queries != 3 || host.Errors != 1 {
+		t.Errorf("Expected 3 queries with 1 error for host_000001, got %d with %d errors", host.Queries, host.Errors)
+	}
+	if host.MinTime != 10*time.Millisecond || host.MaxTime != 30*time.Millisecond {
+		t.Errorf("Expected min 10ms and max 30ms for host_000001, got %v and %v", host.MinTime, host.MaxTime)
+	}
+	if host.MedianTime != 20*time.Millisecond {
+		t.Errorf("Expected median 20ms for host_000001, got %v", host.MedianTime)
+	}
+}
+
+func TestHostsSortedBySlowest(t *testing.T) {
+	s := New()
+
+	for i, hostname := range []string{"fast", "slow", "medium"} {
+		for j := 0; j < 10; j++ {
+			s.Add(Sample{Hostname: hostname, Duration: time.Duration([]int{1, 100, 10}[i]) * time.Millisecond})
+		}
+	}
+
+	s.Compute()
+
+	var got []string
+	for _, h := range s.Hosts {
+		got = append(got, h.Hostname)
+	}
+	want := []string{"slow", "medium", "fast"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected hosts ordered %v, got %v", want, got)
+	}
+}
+
+func TestPrintTopHosts(t *testing.T) {
+	s := New()
+	s.TopHosts = 1
+
+	s.Add(Sample{Hostname: "host_000001", Duration: time.Millisecond})
+	s.Add(Sample{Hostname: "host_000002", Duration: 2 * time.Millisecond})
+	s.Compute()
+
+	var buf bytes.Buffer
+	s.Print(&buf)
+	output := buf.String()
+
+	if !strings.Contains(output, "top 1 of 2") {
+		t.Errorf("Expected slowest hosts header, got:\n%s", output)
+	}
+	if !strings.Contains(output, "host_000002") || strings.Contains(output, "host_000001") {
+		t.Errorf("Expected only host_000002 to be listed, got:\n%s", output)
+	}
+}
+
+func TestAddPerTemplate(t *testing.T) {
+	s := New()
+
+	s.Add(Sample{Hostname: "host_000001", Template: "max_min", Duration: 10 * time.Millisecond})
+	s.Add(Sample{Hostname: "host_000001", Template: "avg", Duration: 30 * time.Millisecond})
+	s.Add(Sample{Hostname: "host_000002", Template: "max_min", Err: errors.New("query failed")})
+
+	s.Compute()
+
+	if len(s.Templates) != 2 {
+		t.Fatalf("Expected 2 templates, got %d", len(s.Templates))
+	}
+	if s.Templates[0].Name != "max_min" || s.Templates[0].Queries != 2 || s.Templates[0].Errors != 1 {
+		t.Errorf("Unexpected max_min summary: %+v", s.Templates[0])
+	}
+	if s.Templates[1].Name != "avg" || s.Templates[1].MaxTime != 30*time.Millisecond {
+		t.Errorf("Unexpected avg summary: %+v", s.Templates[1])
+	}
+
+	var buf bytes.Buffer
+	s.Print(&buf)
+	if !strings.Contains(buf.String(), "Query Templates:") {
+		t.Errorf("Expected query templates section, got:\n%s", buf.String())
 	}
 }
@@ -0,0 +1,47 @@
+package stats
+
+import "time"
+
+// Summary holds latency statistics for a subset of the queries, such as the queries of a single host
+type Summary struct {
+	Queries    int
+	Errors     int
+	MinTime    time.Duration
+	MedianTime time.Duration
+	MaxTime    time.Duration
+	P95        time.Duration // 95th percentile
+	P99        time.Duration // 99th percentile
+
+	latencies recorder
+}
+
+// HostSummary holds the statistics of the queries for a single hostname
+type HostSummary struct {
+	Hostname string
+	Summary
+}
+
+// record adds a successful query duration to the summary
+func (s *Summary) record(duration time.Duration) {
+	s.Queries++
+	s.latencies.Record(duration)
+}
+
+// recordError counts a failed query in the summary
+func (s *Summary) recordError() {
+	s.Queries++
+	s.Errors++
+}
+
+// compute calculates the summary statistics from the recorded durations
+func (s *Summary) compute() {
+	if s.latencies.Count() == 0 {
+		return
+	}
+
+	s.MinTime = s.latencies.Min()
+	s.MaxTime = s.latencies.Max()
+	s.MedianTime = s.latencies.Percentile(50)
+	s.P95 = s.latencies.Percentile(95)
+	s.P99 = s.latencies.Percentile(99)
+}
@@ -0,0 +1,188 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// SweepPoint holds the outcome of one run of a worker-count sweep
+type SweepPoint struct {
+	Workers    int
+	Queries    int
+	Errors     int
+	ErrorRate  float64 // share of the queries that failed
+	Throughput float64 // successful queries per second, leaving out the warm-up phase
+	P50        time.Duration
+	P99        time.Duration
+	// SLOLatency is the latency at the percentile of the latency SLO
+	SLOLatency time.Duration
+	// WithinSLO reports whether the run met the latency SLO, if any, without exceeding
+	// the maximum error rate
+	WithinSLO bool
+}
+
+// Sweep compares the runs of the same input with different numbers of workers and
+// recommends the concurrency giving the highest throughput within a latency SLO
+type Sweep struct {
+	// SLO is the latency not to exceed at SLOPercentile, 0 recommends the highest throughput
+	SLO           time.Duration
+	SLOPercentile float64
+	// MaxErrorRate is the highest share of failed queries of the runs within the SLO
+	MaxErrorRate float64
+	Points       []SweepPoint
+}
+
+// NewSweep creates a sweep with a latency SLO at the given percentile, e.g. 50ms at P99, and
+// the highest share of failed queries tolerated, e.g. 0.01 for 1%. A zero SLO recommends the
+// setting with the highest throughput among the ones within the error rate.
+func NewSweep(slo time.Duration, percentile, maxErrorRate float64) *Sweep {
+	return &Sweep{SLO: slo, SLOPercentile: percentile, MaxErrorRate: maxErrorRate}
+}
+
+// Add records the statistics of the run with the given number of workers.
+// Compute must be called on the statistics before Add.
+func (sw *Sweep) Add(workers int, s *Statistics) {
+	point := SweepPoint{
+		Workers:    workers,
+		Queries:    s.TotalQueries,
+		Errors:     s.FailedQueries,
+		P50:        s.Percentile(50),
+		P99:        s.Percentile(99),
+		SLOLatency: s.Percentile(sw.SLOPercentile),
+	}
+	if s.TotalQueries > 0 {
+		// QPS counts the failed queries too, over the same window
+		point.ErrorRate = float64(s.FailedQueries) / float64(s.TotalQueries)
+		point.Throughput = s.QPS * float64(s.SuccessfulQueries()) / float64(s.TotalQueries)
+	}
+	point.WithinSLO = s.SuccessfulQueries() > 0 && point.ErrorRate <= sw.MaxErrorRate &&
+		(sw.SLO == 0 || point.SLOLatency <= sw.SLO)
+
+	sw.Points = append(sw.Points, point)
+}
+
+// Recommended returns the point with the highest throughput within the SLO,
+// the one with fewer workers on ties. It returns false if no run met the SLO.
+func (sw *Sweep) Recommended() (SweepPoint, bool) {
+	var best SweepPoint
+	found := false
+	for _, point := range sw.Points {
+		if !point.WithinSLO {
+			continue
+		}
+		if !found || point.Throughput > best.Throughput ||
+			(point.Throughput == best.Throughput && point.Workers < best.Workers) {
+			best, found = point, true
+		}
+	}
+	return best, found
+}
+
+// Print outputs the sweep table and the recommended concurrency
+func (sw *Sweep) Print(out io.Writer) {
+	sloLabel := PercentileLabel(sw.SLOPercentile)
+
+	_, _ = fmt.Fprintln(out, "\n"+strings.Repeat("=", 60))
+	_, _ = fmt.Fprintln(out, "WORKER SWEEP RESULTS")
+	_, _ = fmt.Fprintln(out, strings.Repeat("=", 60))
+
+	// The latency at the SLO percentile gets its own column unless it is already shown
+	sloColumn := sw.SLO > 0 && sw.SLOPercentile != 50 && sw.SLOPercentile != 99
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	header := "  Workers\tQueries\tErrors\tQueries/s\tP50\tP99"
+	if sloColumn {
+		header += "\t" + sloLabel
+	}
+	header += "\tWithin SLO"
+	_, _ = fmt.Fprintln(tw, header)
+	for _, p := range sw.Points {
+		_, _ = fmt.Fprintf(tw, "  %d\t%d\t%d\t%.1f\t%v\t%v", p.Workers, p.Queries, p.Errors, p.Throughput, p.P50, p.P99)
+		if sloColumn {
+			_, _ = fmt.Fprintf(tw, "\t%v", p.SLOLatency)
+		}
+		within := "no"
+		if p.WithinSLO {
+			within = "yes"
+		}
+		_, _ = fmt.Fprintf(tw, "\t%s\n", within)
+	}
+	_ = tw.Flush()
+
+	_, _ = fmt.Fprintln(out)
+	best, found := sw.Recommended()
+	switch {
+	case !found && sw.SLO > 0:
+		_, _ = fmt.Fprintf(out, "No setting met the SLO of %v at %s with at most %.1f%% errors\n",
+			sw.SLO, sloLabel, sw.MaxErrorRate*100)
+	case !found:
+		_, _ = fmt.Fprintf(out, "No setting had at most %.1f%% errors\n", sw.MaxErrorRate*100)
+	case sw.SLO > 0:
+		_, _ = fmt.Fprintf(out, "Recommended concurrency: %d workers (%.1f queries/s, %s %v within the SLO of %v)\n",
+			best.Workers, best.Throughput, sloLabel, best.SLOLatency, sw.SLO)
+	default:
+		_, _ = fmt.Fprintf(out, "Highest throughput: %d workers (%.1f queries/s), set -slo to recommend a concurrency within a latency target\n",
+			best.Workers, best.Throughput)
+	}
+	_, _ = fmt.Fprintln(out, strings.Repeat("=", 60))
+}
+
+// SweepSchemaVersion is the version of the JSON document written by Sweep.WriteJSON,
+// versioned apart from the JSON report of a single run
+const SweepSchemaVersion = 1
+
+type jsonSweep struct {
+	SchemaVersion int              `json:"schema_version"`
+	SLONs         int64            `json:"slo_ns"` // 0 without SLO
+	SLOPercentile float64          `json:"slo_percentile"`
+	MaxErrorRate  float64          `json:"max_error_rate"`
+	Points        []jsonSweepPoint `json:"points"`
+	Recommended   *int             `json:"recommended_workers"` // null when no setting met the SLO
+}
+
+type jsonSweepPoint struct {
+	Workers      int     `json:"workers"`
+	Queries      int     `json:"queries"`
+	Errors       int     `json:"errors"`
+	ErrorRate    float64 `json:"error_rate"`
+	Throughput   float64 `json:"queries_per_second"`
+	P50Ns        int64   `json:"p50_ns"`
+	P99Ns        int64   `json:"p99_ns"`
+	SLOLatencyNs int64   `json:"slo_latency_ns"`
+	WithinSLO    bool    `json:"within_slo"`
+}
+
+// WriteJSON writes the sweep as a JSON document to the provided output
+func (sw *Sweep) WriteJSON(out io.Writer) error {
+	report := jsonSweep{
+		SchemaVersion: SweepSchemaVersion,
+		SLONs:         sw.SLO.Nanoseconds(),
+		SLOPercentile: sw.SLOPercentile,
+		MaxErrorRate:  sw.MaxErrorRate,
+		Points:        make([]jsonSweepPoint, 0, len(sw.Points)),
+	}
+	for _, p := range sw.Points {
+		report.Points = append(report.Points, jsonSweepPoint{
+			Workers:      p.Workers,
+			Queries:      p.Queries,
+			Errors:       p.Errors,
+			ErrorRate:    p.ErrorRate,
+			Throughput:   p.Throughput,
+			P50Ns:        p.P50.Nanoseconds(),
+			P99Ns:        p.P99.Nanoseconds(),
+			SLOLatencyNs: p.SLOLatency.Nanoseconds(),
+			WithinSLO:    p.WithinSLO,
+		})
+	}
+	if best, found := sw.Recommended(); found {
+		report.Recommended = &best.Workers
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
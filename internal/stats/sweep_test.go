@@ -0,0 +1,139 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sweepRun returns the statistics of a run of the given duration with queries of the given latency
+func sweepRun(queries int, latency, duration time.Duration) *Statistics {
+	s := New()
+	for i := 0; i < queries; i++ {
+		s.Record(latency)
+	}
+	s.ProcessingTime = duration
+	s.Compute()
+	return s
+}
+
+func TestSweepRecommended(t *testing.T) {
+	sw := NewSweep(20*time.Millisecond, 99, 0.01)
+	sw.Add(1, sweepRun(100, 5*time.Millisecond, time.Second))
+	sw.Add(2, sweepRun(200, 8*time.Millisecond, time.Second))
+	sw.Add(4, sweepRun(300, 15*time.Millisecond, time.Second))
+	sw.Add(8, sweepRun(320, 40*time.Millisecond, time.Second)) // highest throughput, out of SLO
+
+	if len(sw.Points) != 4 {
+		t.Fatalf("Expected 4 points, got %d", len(sw.Points))
+	}
+	if sw.Points[2].Throughput != 300 || sw.Points[2].P99 != 15*time.Millisecond {
+		t.Errorf("Unexpected point %+v", sw.Points[2])
+	}
+	if sw.Points[3].WithinSLO {
+		t.Errorf("Expected the 8 workers run to be out of the SLO: %+v", sw.Points[3])
+	}
+
+	best, found := sw.Recommended()
+	if !found || best.Workers != 4 {
+		t.Errorf("Expected 4 workers to be recommended, got %+v (found %v)", best, found)
+	}
+
+	var out strings.Builder
+	sw.Print(&out)
+	if !strings.Contains(out.String(), "Recommended concurrency: 4 workers") {
+		t.Errorf("Expected the recommendation in the report:\n%s", out.String())
+	}
+
+	var buf bytes.Buffer
+	if err := sw.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() failed: %v", err)
+	}
+	var report jsonSweep
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("WriteJSON() produced invalid JSON: %v", err)
+	}
+	if report.SchemaVersion != SweepSchemaVersion {
+		t.Errorf("Expected schema version %d, got %d", SweepSchemaVersion, report.SchemaVersion)
+	}
+	if report.Recommended == nil || *report.Recommended != 4 || len(report.Points) != 4 {
+		t.Errorf("Unexpected JSON sweep %+v", report)
+	}
+}
+
+func TestSweepNoSettingWithinSLO(t *testing.T) {
+	sw := NewSweep(time.Millisecond, 99, 0.01)
+	sw.Add(1, sweepRun(100, 5*time.Millisecond, time.Second))
+	sw.Add(2, sweepRun(0, 0, time.Second)) // no successful query
+
+	if _, found := sw.Recommended(); found {
+		t.Error("Expected no recommendation")
+	}
+
+	var out strings.Builder
+	sw.Print(&out)
+	if !strings.Contains(out.String(), "No setting met the SLO") {
+		t.Errorf("Expected no recommendation in the report:\n%s", out.String())
+	}
+}
+
+func TestSweepWithoutSLO(t *testing.T) {
+	sw := NewSweep(0, 99, 0.01)
+	sw.Add(1, sweepRun(100, 5*time.Millisecond, time.Second))
+	sw.Add(2, sweepRun(150, 50*time.Millisecond, time.Second))
+
+	best, found := sw.Recommended()
+	if !found || best.Workers != 2 {
+		t.Errorf("Expected the highest throughput, got %+v (found %v)", best, found)
+	}
+}
+
+func TestSweepErrorRate(t *testing.T) {
+	sw := NewSweep(20*time.Millisecond, 99, 0.01)
+	sw.Add(1, sweepRun(100, 5*time.Millisecond, time.Second))
+
+	// Most queries fail fast at 2 workers: more queries per second, fewer successful ones
+	failing := New()
+	for i := 0; i < 80; i++ {
+		failing.Record(5 * time.Millisecond)
+	}
+	for i := 0; i < 120; i++ {
+		failing.RecordError()
+	}
+	failing.ProcessingTime = time.Second
+	failing.Compute()
+	sw.Add(2, failing)
+
+	point := sw.Points[1]
+	if point.Throughput != 80 || point.ErrorRate != 0.6 {
+		t.Errorf("Expected 80 successful queries/s and a 0.6 error rate, got %+v", point)
+	}
+	if point.WithinSLO {
+		t.Errorf("Expected the 2 workers run to be out of the SLO: %+v", point)
+	}
+
+	best, found := sw.Recommended()
+	if !found || best.Workers != 1 {
+		t.Errorf("Expected 1 worker to be recommended, got %+v (found %v)", best, found)
+	}
+}
+
+func TestSweepThroughputLeavesOutWarmup(t *testing.T) {
+	s := New()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.Metadata.StartTime = start
+	s.Add(Sample{Duration: time.Millisecond, End: start.Add(time.Second), Warmup: true})
+	for i := 0; i < 100; i++ {
+		s.Add(Sample{Duration: time.Millisecond, End: start.Add(2 * time.Second)})
+	}
+	s.ProcessingTime = 3 * time.Second
+	s.Compute()
+
+	sw := NewSweep(0, 99, 0.01)
+	sw.Add(1, s)
+	if got := sw.Points[0].Throughput; got != 50 {
+		t.Errorf("Expected 50 queries/s over the 2s after the warm-up, got %.1f", got)
+	}
+}
@@ -0,0 +1,49 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// TemplateSummary holds the statistics of the queries run from a single query template
+type TemplateSummary struct {
+	Name string
+	Summary
+}
+
+// template returns the summary of the given template, creating it if needed
+// Must be called with mutex locked
+func (s *Statistics) template(name string) *TemplateSummary {
+	for _, template := range s.templates {
+		if template.Name == name {
+			return template
+		}
+	}
+
+	template := &TemplateSummary{Name: name, Summary: s.newSummary()}
+	s.templates = append(s.templates, template)
+	return template
+}
+
+// computeTemplates calculates the per-template statistics
+// Must be called with mutex locked
+func (s *Statistics) computeTemplates() {
+	s.Templates = make([]TemplateSummary, 0, len(s.templates))
+	for _, template := range s.templates {
+		template.compute()
+		s.Templates = append(s.Templates, *template)
+	}
+}
+
+// printTemplates outputs the per-template statistics
+func (s *Statistics) printTemplates(out io.Writer) {
+	_, _ = fmt.Fprintln(out, "\nQuery Templates:")
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "  Template\tQueries\tErrors\tMinimum\tMedian\tMaximum\tP95\tP99")
+	for _, t := range s.Templates {
+		_, _ = fmt.Fprintf(tw, "  %s\t%d\t%d\t%v\t%v\t%v\t%v\t%v\n",
+			t.Name, t.Queries, t.Errors, t.MinTime, t.MedianTime, t.MaxTime, t.P95, t.P99)
+	}
+	_ = tw.Flush()
+}
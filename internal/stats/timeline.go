@@ -0,0 +1,116 @@
+package stats
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// DefaultInterval is the default width of the intervals of the timeline
+const DefaultInterval = time.Second
+
+// timelinePrecision caps the precision of the per-interval histograms, as long runs
+// have many intervals. Their percentiles are within 5% of the exact values.
+const timelinePrecision = 1
+
+// IntervalSummary holds the statistics of the queries completed during an interval of the run
+type IntervalSummary struct {
+	Start    time.Duration // offset of the interval from the start of the run
+	Duration time.Duration // width of the interval, shorter than Interval for the last one
+	Rows     int64         // rows returned by the successful queries
+	Summary
+}
+
+// Throughput returns the number of queries completed per second during the interval
+func (i IntervalSummary) Throughput() float64 {
+	if i.Duration <= 0 {
+		return 0
+	}
+	return float64(i.Queries) / i.Duration.Seconds()
+}
+
+// interval returns the summary of the interval the completion time falls in, creating
+// it and any interval before it if needed. It returns nil when the completion time or
+// the start time of the run is unknown.
+// Must be called with mutex locked
+func (s *Statistics) interval(end time.Time) *IntervalSummary {
+	if end.IsZero() || s.Metadata.StartTime.IsZero() || s.Interval <= 0 {
+		return nil
+	}
+
+	index := max(int(end.Sub(s.Metadata.StartTime)/s.Interval), 0)
+	for len(s.intervals) <= index {
+		s.intervals = append(s.intervals, &IntervalSummary{
+			Start:   time.Duration(len(s.intervals)) * s.Interval,
+			Summary: Summary{latencies: newRecorder(min(s.precision, timelinePrecision))},
+		})
+	}
+	return s.intervals[index]
+}
+
+// computeThroughput calculates the query and row throughputs of the run
+// Must be called with mutex locked
+func (s *Statistics) computeThroughput() {
+	elapsed := s.ProcessingTime
+	if !s.warmupEnd.IsZero() && !s.Metadata.StartTime.IsZero() {
+		elapsed -= s.warmupEnd.Sub(s.Metadata.StartTime)
+	}
+	if elapsed <= 0 {
+		return
+	}
+	s.QPS = float64(s.TotalQueries) / elapsed.Seconds()
+	s.RowsPerSecond = float64(s.TotalRows) / elapsed.Seconds()
+}
+
+// computeTimeline calculates the per-interval statistics
+// Must be called with mutex locked
+func (s *Statistics) computeTimeline() {
+	s.Timeline = make([]IntervalSummary, 0, len(s.intervals))
+	for _, interval := range s.intervals {
+		interval.compute()
+		interval.Duration = s.Interval
+		if s.ProcessingTime > interval.Start && s.ProcessingTime-interval.Start < s.Interval {
+			interval.Duration = s.ProcessingTime - interval.Start
+		}
+		s.Timeline = append(s.Timeline, *interval)
+	}
+}
+
+// printTimeline outputs the per-interval statistics
+func (s *Statistics) printTimeline(out io.Writer) {
+	_, _ = fmt.Fprintf(out, "\nTimeline (every %v):\n", s.Interval)
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "  Start\tQueries\tErrors\tQueries/s\tRows\tMedian\tP99")
+	for _, i := range s.Timeline {
+		_, _ = fmt.Fprintf(tw, "  %v\t%d\t%d\t%.1f\t%d\t%v\t%v\n",
+			i.Start, i.Queries, i.Errors, i.Throughput(), i.Rows, i.MedianTime, i.P99)
+	}
+	_ = tw.Flush()
+}
+
+// WriteTimelineCSV writes the timeline as CSV to the provided output, with a header row and
+// one row per interval. Intervals start at an offset in seconds from the start of the run
+// and latencies are expressed in nanoseconds. Compute must be called before WriteTimelineCSV.
+func (s *Statistics) WriteTimelineCSV(out io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := csv.NewWriter(out)
+	_ = w.Write([]string{"start_s", "queries", "errors", "queries_per_second", "rows", "median_ns", "p99_ns"})
+	for _, i := range s.Timeline {
+		_ = w.Write([]string{
+			strconv.FormatFloat(i.Start.Seconds(), 'f', -1, 64),
+			strconv.Itoa(i.Queries),
+			strconv.Itoa(i.Errors),
+			strconv.FormatFloat(i.Throughput(), 'f', 1, 64),
+			strconv.FormatInt(i.Rows, 10),
+			strconv.FormatInt(i.MedianTime.Nanoseconds(), 10),
+			strconv.FormatInt(i.P99.Nanoseconds(), 10),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,108 @@
+package stats
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeline(t *testing.T) {
+	s := New()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.Metadata.StartTime = start
+
+	for i := range 10 {
+		s.Add(Sample{Hostname: "host_000001", End: start.Add(time.Duration(i) * 100 * time.Millisecond),
+			Duration: time.Duration(i+1) * time.Millisecond, Rows: 2})
+	}
+	s.Add(Sample{Hostname: "host_000001", End: start.Add(2500 * time.Millisecond), Err: errors.New("query failed")})
+	s.ProcessingTime = 2600 * time.Millisecond
+	s.Compute()
+
+	if len(s.Timeline) != 3 {
+		t.Fatalf("Expected 3 intervals, got %d", len(s.Timeline))
+	}
+
+	first, second, last := s.Timeline[0], s.Timeline[1], s.Timeline[2]
+	if first.Start != 0 || first.Queries != 10 || first.Rows != 20 || first.MaxTime != 10*time.Millisecond {
+		t.Errorf("Unexpected first interval %+v", first)
+	}
+	if second.Start != time.Second || second.Queries != 0 {
+		t.Errorf("Expected an empty second interval, got %+v", second)
+	}
+	if last.Start != 2*time.Second || last.Queries != 1 || last.Errors != 1 {
+		t.Errorf("Unexpected last interval %+v", last)
+	}
+	if last.Duration != 600*time.Millisecond {
+		t.Errorf("Expected the last interval to last 600ms, got %v", last.Duration)
+	}
+	if got := first.Throughput(); got != 10 {
+		t.Errorf("Expected 10 queries/s in the first interval, got %v", got)
+	}
+
+	if s.TotalRows != 20 {
+		t.Errorf("Expected 20 rows, got %d", s.TotalRows)
+	}
+	if math.Abs(s.QPS-11/2.6) > 1e-9 || math.Abs(s.RowsPerSecond-20/2.6) > 1e-9 {
+		t.Errorf("Unexpected throughput %v queries/s, %v rows/s", s.QPS, s.RowsPerSecond)
+	}
+}
+
+func TestThroughputExcludesWarmup(t *testing.T) {
+	s := New()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.Metadata.StartTime = start
+
+	s.Add(Sample{Hostname: "host_000001", End: start.Add(time.Second), Duration: time.Millisecond, Rows: 5, Warmup: true})
+	for i := range 4 {
+		s.Add(Sample{Hostname: "host_000001", End: start.Add(time.Second + time.Duration(i)*time.Millisecond),
+			Duration: time.Millisecond, Rows: 5})
+	}
+	s.ProcessingTime = 3 * time.Second
+	s.Compute()
+
+	if s.QPS != 2 || s.RowsPerSecond != 10 {
+		t.Errorf("Expected 2 queries/s and 10 rows/s after the warm-up, got %v and %v", s.QPS, s.RowsPerSecond)
+	}
+	if len(s.Timeline) != 2 || s.Timeline[0].Queries != 0 {
+		t.Errorf("Expected the warm-up to be left out of the timeline, got %+v", s.Timeline)
+	}
+}
+
+func TestTimelineOutput(t *testing.T) {
+	s := New()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.Metadata.StartTime = start
+	s.Interval = 500 * time.Millisecond
+
+	s.Add(Sample{Hostname: "host_000001", End: start.Add(100 * time.Millisecond), Duration: 2 * time.Millisecond, Rows: 3})
+	s.Add(Sample{Hostname: "host_000001", End: start.Add(700 * time.Millisecond), Duration: 4 * time.Millisecond, Rows: 1})
+	s.ProcessingTime = time.Second
+	s.Compute()
+
+	var out strings.Builder
+	s.Print(&out)
+	if strings.Contains(out.String(), "Timeline") {
+		t.Errorf("Expected no timeline unless requested:\n%s", out.String())
+	}
+
+	s.PrintTimeline = true
+	out.Reset()
+	s.Print(&out)
+	if !strings.Contains(out.String(), "Timeline (every 500ms):") {
+		t.Errorf("Expected the timeline section in the report:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := s.WriteTimelineCSV(&out); err != nil {
+		t.Fatalf("WriteTimelineCSV() failed: %v", err)
+	}
+	want := "start_s,queries,errors,queries_per_second,rows,median_ns,p99_ns\n" +
+		"0,1,0,2.0,3,2000000,2000000\n" +
+		"0.5,1,0,2.0,1,4000000,4000000\n"
+	if out.String() != want {
+		t.Errorf("Expected CSV:\n%s\ngot:\n%s", want, out.String())
+	}
+}
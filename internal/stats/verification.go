@@ -0,0 +1,136 @@
+package stats
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// Verdict is the outcome of the verification of a query result
+type Verdict int
+
+const (
+	// VerdictNone marks the results that were not verified
+	VerdictNone Verdict = iota
+	// VerdictMatch marks the results equal to their expected result
+	VerdictMatch
+	// VerdictMismatch marks the results differing from their expected result
+	VerdictMismatch
+	// VerdictUnknown marks the results whose expected result is unknown, such as the
+	// results missing from the golden file or whose reference query failed
+	VerdictUnknown
+)
+
+// Verification summarizes the verification of the query results against their expected results
+type Verification struct {
+	Method     string // how the expected results are obtained, e.g. "golden file"
+	Checked    int    // results verified, including the ones whose expected result is unknown
+	Mismatches int
+	Unknown    int
+	// Hosts holds the verification of the results of each hostname, from the most
+	// to the least mismatches
+	Hosts []HostVerification
+
+	hosts map[string]*HostVerification
+}
+
+// HostVerification holds the verification of the results of the queries for a single hostname
+type HostVerification struct {
+	Hostname   string
+	Checked    int
+	Mismatches int
+	Unknown    int
+	Sample     string // description of the first mismatch, or of the first unknown result without any mismatch
+}
+
+// SetVerification enables the verification statistics, the expected results being obtained with the given method
+func (s *Statistics) SetVerification(method string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Verification = &Verification{Method: method, hosts: make(map[string]*HostVerification)}
+}
+
+// recordVerification adds the verdict of a query result to the verification statistics
+// Must be called with mutex locked
+func (s *Statistics) recordVerification(sample Sample) {
+	v := s.Verification
+	if v == nil || sample.Verdict == VerdictNone {
+		return
+	}
+
+	host, ok := v.hosts[sample.Hostname]
+	if !ok {
+		host = &HostVerification{Hostname: sample.Hostname}
+		v.hosts[sample.Hostname] = host
+	}
+	v.Checked++
+	host.Checked++
+	switch sample.Verdict {
+	case VerdictMismatch:
+		v.Mismatches++
+		if host.Mismatches == 0 {
+			host.Sample = sample.VerdictDetail
+		}
+		host.Mismatches++
+	case VerdictUnknown:
+		v.Unknown++
+		if host.Mismatches == 0 && host.Unknown == 0 {
+			host.Sample = sample.VerdictDetail
+		}
+		host.Unknown++
+	}
+}
+
+// computeVerification sorts the hosts from the most to the least mismatches
+// Must be called with mutex locked
+func (s *Statistics) computeVerification() {
+	v := s.Verification
+	if v == nil {
+		return
+	}
+
+	v.Hosts = make([]HostVerification, 0, len(v.hosts))
+	for _, host := range v.hosts {
+		v.Hosts = append(v.Hosts, *host)
+	}
+	slices.SortFunc(v.Hosts, func(a, b HostVerification) int {
+		if a.Mismatches != b.Mismatches {
+			return cmp.Compare(b.Mismatches, a.Mismatches)
+		}
+		if a.Unknown != b.Unknown {
+			return cmp.Compare(b.Unknown, a.Unknown)
+		}
+		return strings.Compare(a.Hostname, b.Hostname)
+	})
+}
+
+// printVerification outputs the verification summary and the hosts with mismatches or
+// unknown results, listing at most TopHosts of them
+func (s *Statistics) printVerification(out io.Writer) {
+	v := s.Verification
+	_, _ = fmt.Fprintf(out, "\nVerification (against the %s):\n", v.Method)
+	_, _ = fmt.Fprintf(out, "  Results checked:  %d (%d mismatches, %d unknown)\n", v.Checked, v.Mismatches, v.Unknown)
+
+	var failing []HostVerification
+	for _, host := range v.Hosts {
+		if host.Mismatches > 0 || host.Unknown > 0 {
+			failing = append(failing, host)
+		}
+	}
+	if len(failing) == 0 || s.TopHosts <= 0 {
+		return
+	}
+
+	listed := failing[:min(s.TopHosts, len(failing))]
+	_, _ = fmt.Fprintf(out, "Hosts failing verification (top %d of %d):\n", len(listed), len(failing))
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "  Hostname\tChecked\tMismatches\tUnknown\tSample")
+	for _, h := range listed {
+		_, _ = fmt.Fprintf(tw, "  %s\t%d\t%d\t%d\t%s\n", h.Hostname, h.Checked, h.Mismatches, h.Unknown, h.Sample)
+	}
+	_ = tw.Flush()
+}
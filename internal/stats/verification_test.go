@@ -0,0 +1,67 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerification(t *testing.T) {
+	s := New()
+	s.SetVerification("golden file")
+
+	for range 3 {
+		s.Add(Sample{Hostname: "host_000001", Duration: time.Millisecond, Verdict: VerdictMatch})
+	}
+	s.Add(Sample{Hostname: "host_000002", Duration: time.Millisecond, Verdict: VerdictUnknown, VerdictDetail: "missing from the golden file"})
+	s.Add(Sample{Hostname: "host_000003", Duration: time.Millisecond, Verdict: VerdictMismatch, VerdictDetail: "expected 60 rows"})
+	s.Add(Sample{Hostname: "host_000003", Duration: time.Millisecond, Verdict: VerdictMismatch, VerdictDetail: "expected 59 rows"})
+	// Warm-up queries are verified too, unlike the queries that were not
+	s.Add(Sample{Hostname: "host_000003", Duration: time.Millisecond, Verdict: VerdictMatch, Warmup: true})
+	s.Add(Sample{Hostname: "host_000004", Duration: time.Millisecond})
+	s.Compute()
+
+	v := s.Verification
+	if v.Checked != 7 || v.Mismatches != 2 || v.Unknown != 1 {
+		t.Errorf("Expected 7 results checked with 2 mismatches and 1 unknown, got %+v", v)
+	}
+	if len(v.Hosts) != 3 {
+		t.Fatalf("Expected 3 hosts verified, got %+v", v.Hosts)
+	}
+	if first := v.Hosts[0]; first.Hostname != "host_000003" || first.Checked != 3 || first.Mismatches != 2 || first.Sample != "expected 60 rows" {
+		t.Errorf("Expected host_000003 to have the most mismatches, got %+v", first)
+	}
+	if second := v.Hosts[1]; second.Hostname != "host_000002" || second.Unknown != 1 {
+		t.Errorf("Expected host_000002 to follow, got %+v", second)
+	}
+
+	var out strings.Builder
+	s.Print(&out)
+	for _, want := range []string{"Verification (against the golden file):", "Results checked:  7 (2 mismatches, 1 unknown)",
+		"Hosts failing verification (top 2 of 2):", "expected 60 rows"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in the report:\n%s", want, out.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := s.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() failed: %v", err)
+	}
+	var report struct {
+		Verification *struct {
+			Mismatches int `json:"mismatches"`
+			Hosts      []struct {
+				Hostname string `json:"hostname"`
+			} `json:"hosts"`
+		} `json:"verification"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if report.Verification == nil || report.Verification.Mismatches != 2 || len(report.Verification.Hosts) != 3 {
+		t.Errorf("Unexpected verification section %+v", report.Verification)
+	}
+}
@@ -0,0 +1,18 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+)
+
+// printWarmup outputs the statistics of the warm-up queries
+func (s *Statistics) printWarmup(out io.Writer) {
+	_, _ = fmt.Fprintln(out, "\nWarm-up (excluded from the statistics above):")
+	_, _ = fmt.Fprintf(out, "  Queries:     %d (%d errors)\n", s.Warmup.Queries, s.Warmup.Errors)
+	if s.Warmup.Queries > s.Warmup.Errors {
+		_, _ = fmt.Fprintf(out, "  Minimum:     %v\n", s.Warmup.MinTime)
+		_, _ = fmt.Fprintf(out, "  Median:      %v\n", s.Warmup.MedianTime)
+		_, _ = fmt.Fprintf(out, "  Maximum:     %v\n", s.Warmup.MaxTime)
+		_, _ = fmt.Fprintf(out, "  P99:         %v\n", s.Warmup.P99)
+	}
+}
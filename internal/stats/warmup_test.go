@@ -0,0 +1,70 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddWarmup(t *testing.T) {
+	s := New()
+
+	// Slow cold queries followed by the measured ones
+	s.Add(Sample{Hostname: "host_000001", Duration: time.Second, Warmup: true})
+	s.Add(Sample{Hostname: "host_000001", Duration: 500 * time.Millisecond, Warmup: true})
+	s.Add(Sample{Hostname: "host_000001", Err: errors.New("query failed"), Warmup: true})
+	for i := 1; i <= 10; i++ {
+		s.Add(Sample{Hostname: "host_000001", Duration: time.Duration(i) * time.Millisecond})
+	}
+	s.Compute()
+
+	if s.TotalQueries != 10 || s.FailedQueries != 0 {
+		t.Errorf("Expected 10 measured queries without errors, got %d and %d errors", s.TotalQueries, s.FailedQueries)
+	}
+	if s.MaxTime != 10*time.Millisecond {
+		t.Errorf("Expected warm-up queries to be excluded from the maximum, got %v", s.MaxTime)
+	}
+	if len(s.Hosts) != 1 || s.Hosts[0].Queries != 10 {
+		t.Errorf("Expected warm-up queries to be excluded from the hosts, got %+v", s.Hosts)
+	}
+
+	if s.Warmup.Queries != 3 || s.Warmup.Errors != 1 {
+		t.Errorf("Expected 3 warm-up queries with 1 error, got %d and %d", s.Warmup.Queries, s.Warmup.Errors)
+	}
+	if s.Warmup.MinTime != 500*time.Millisecond || s.Warmup.MaxTime != time.Second {
+		t.Errorf("Unexpected warm-up min/max %v and %v", s.Warmup.MinTime, s.Warmup.MaxTime)
+	}
+
+	var out strings.Builder
+	s.Print(&out)
+	if !strings.Contains(out.String(), "Warm-up (excluded from the statistics above):") {
+		t.Errorf("Expected the warm-up section in the report:\n%s", out.String())
+	}
+
+	var buf bytes.Buffer
+	if err := s.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() failed: %v", err)
+	}
+	var report jsonReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("WriteJSON() produced invalid JSON: %v", err)
+	}
+	if report.Warmup == nil || report.Warmup.Queries != 3 {
+		t.Errorf("Unexpected JSON warm-up %+v", report.Warmup)
+	}
+}
+
+func TestNoWarmup(t *testing.T) {
+	s := New()
+	s.Add(Sample{Hostname: "host_000001", Duration: time.Millisecond})
+	s.Compute()
+
+	var out strings.Builder
+	s.Print(&out)
+	if strings.Contains(out.String(), "Warm-up") {
+		t.Errorf("Expected no warm-up section:\n%s", out.String())
+	}
+}
@@ -0,0 +1,127 @@
+package stats
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+// WorkerSummary holds the statistics of the queries executed by a single worker
+type WorkerSummary struct {
+	ID       int
+	BusyTime time.Duration // time spent executing queries
+	IdleTime time.Duration // time spent waiting for queries to be assigned
+	Summary
+}
+
+// Utilization returns the fraction of the worker's time spent executing queries
+func (w WorkerSummary) Utilization() float64 {
+	total := w.BusyTime + w.IdleTime
+	if total == 0 {
+		return 0
+	}
+	return float64(w.BusyTime) / float64(total)
+}
+
+// LoadImbalance summarizes how evenly queries were distributed across workers
+type LoadImbalance struct {
+	MaxQueries int
+	MinQueries int
+	// CoefficientOfVariation is the standard deviation of the number of queries per worker
+	// divided by its mean. 0 means a perfectly even distribution.
+	CoefficientOfVariation float64
+}
+
+// RecordWorkerTime adds the busy and idle time of a worker to its statistics
+func (s *Statistics) RecordWorkerTime(workerID int, busy, idle time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	worker := s.worker(workerID)
+	worker.BusyTime += busy
+	worker.IdleTime += idle
+}
+
+// worker returns the summary of the given worker, creating it if needed
+// Must be called with mutex locked
+func (s *Statistics) worker(workerID int) *WorkerSummary {
+	worker, ok := s.workers[workerID]
+	if !ok {
+		worker = &WorkerSummary{ID: workerID, Summary: s.newSummary()}
+		s.workers[workerID] = worker
+	}
+	return worker
+}
+
+// computeWorkers calculates the per-worker statistics and the load imbalance
+// Workers that did not receive any query are included so they count towards the imbalance
+// Must be called with mutex locked
+func (s *Statistics) computeWorkers() {
+	for id := 0; id < s.Metadata.Workers; id++ {
+		s.worker(id)
+	}
+
+	s.Workers = make([]WorkerSummary, 0, len(s.workers))
+	for _, worker := range s.workers {
+		worker.compute()
+		s.Workers = append(s.Workers, *worker)
+	}
+	slices.SortFunc(s.Workers, func(a, b WorkerSummary) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	s.Imbalance = loadImbalance(s.Workers)
+}
+
+// loadImbalance calculates the spread of the number of queries across workers
+func loadImbalance(workers []WorkerSummary) LoadImbalance {
+	if len(workers) == 0 {
+		return LoadImbalance{}
+	}
+
+	imbalance := LoadImbalance{
+		MaxQueries: workers[0].Queries,
+		MinQueries: workers[0].Queries,
+	}
+
+	var total float64
+	for _, w := range workers {
+		imbalance.MaxQueries = max(imbalance.MaxQueries, w.Queries)
+		imbalance.MinQueries = min(imbalance.MinQueries, w.Queries)
+		total += float64(w.Queries)
+	}
+
+	mean := total / float64(len(workers))
+	if mean == 0 {
+		return imbalance
+	}
+
+	var variance float64
+	for _, w := range workers {
+		diff := float64(w.Queries) - mean
+		variance += diff * diff
+	}
+	variance /= float64(len(workers))
+	imbalance.CoefficientOfVariation = math.Sqrt(variance) / mean
+
+	return imbalance
+}
+
+// printWorkers outputs the per-worker statistics and the load imbalance summary
+func (s *Statistics) printWorkers(out io.Writer) {
+	_, _ = fmt.Fprintln(out, "\nWorkers:")
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "  Worker\tQueries\tErrors\tBusy\tIdle\tUtilization\tMedian\tP95\tP99")
+	for _, w := range s.Workers {
+		_, _ = fmt.Fprintf(tw, "  %d\t%d\t%d\t%v\t%v\t%.1f%%\t%v\t%v\t%v\n",
+			w.ID, w.Queries, w.Errors, w.BusyTime, w.IdleTime, w.Utilization()*100, w.MedianTime, w.P95, w.P99)
+	}
+	_ = tw.Flush()
+
+	_, _ = fmt.Fprintf(out, "Load imbalance: max %d / min %d queries per worker, coefficient of variation %.2f\n",
+		s.Imbalance.MaxQueries, s.Imbalance.MinQueries, s.Imbalance.CoefficientOfVariation)
+}
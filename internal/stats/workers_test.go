@@ -0,0 +1,92 @@
+package stats
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAddPerWorker(t *testing.T) {
+	s := New()
+	s.Metadata.Workers = 3
+
+	s.Add(Sample{Hostname: "host_000001", WorkerID: 0, Duration: 10 * time.Millisecond})
+	s.Add(Sample{Hostname: "host_000001", WorkerID: 0, Duration: 20 * time.Millisecond})
+	s.Add(Sample{Hostname: "host_000002", WorkerID: 1, Err: errors.New("query failed")})
+	s.RecordWorkerTime(0, 30*time.Millisecond, 10*time.Millisecond)
+
+	s.Compute()
+
+	// Worker 2 never received a query but must still be reported
+	if len(s.Workers) != 3 {
+		t.Fatalf("Expected 3 workers, got %d", len(s.Workers))
+	}
+
+	for i, w := range s.Workers {
+		if w.ID != i {
+			t.Errorf("Expected worker %d at position %d, got worker %d", i, i, w.ID)
+		}
+	}
+
+	w := s.Workers[0]
+	if w.Queries != 2 || w.Errors != 0 {
+		t.Errorf("Expected 2 queries without errors for worker 0, got %d with %d errors", w.Queries, w.Errors)
+	}
+	if w.MaxTime != 20*time.Millisecond {
+		t.Errorf("Expected max 20ms for worker 0, got %v", w.MaxTime)
+	}
+	if w.Utilization() != 0.75 {
+		t.Errorf("Expected utilization 0.75 for worker 0, got %v", w.Utilization())
+	}
+
+	if s.Workers[1].Errors != 1 {
+		t.Errorf("Expected 1 error for worker 1, got %d", s.Workers[1].Errors)
+	}
+	if s.Workers[2].Queries != 0 {
+		t.Errorf("Expected 0 queries for worker 2, got %d", s.Workers[2].Queries)
+	}
+}
+
+func TestLoadImbalance(t *testing.T) {
+	tests := []struct {
+		name    string
+		queries []int
+		want    LoadImbalance
+	}{
+		{
+			name:    "even distribution",
+			queries: []int{10, 10, 10, 10},
+			want:    LoadImbalance{MaxQueries: 10, MinQueries: 10, CoefficientOfVariation: 0},
+		},
+		{
+			name:    "skewed distribution",
+			queries: []int{30, 10},
+			want:    LoadImbalance{MaxQueries: 30, MinQueries: 10, CoefficientOfVariation: 0.5},
+		},
+		{
+			name:    "no queries",
+			queries: []int{0, 0},
+			want:    LoadImbalance{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workers := make([]WorkerSummary, len(tt.queries))
+			for i, q := range tt.queries {
+				workers[i] = WorkerSummary{ID: i, Summary: Summary{Queries: q}}
+			}
+
+			got := loadImbalance(workers)
+			if got.MaxQueries != tt.want.MaxQueries || got.MinQueries != tt.want.MinQueries {
+				t.Errorf("Expected max %d and min %d, got max %d and min %d",
+					tt.want.MaxQueries, tt.want.MinQueries, got.MaxQueries, got.MinQueries)
+			}
+			if math.Abs(got.CoefficientOfVariation-tt.want.CoefficientOfVariation) > 1e-9 {
+				t.Errorf("Expected coefficient of variation %v, got %v",
+					tt.want.CoefficientOfVariation, got.CoefficientOfVariation)
+			}
+		})
+	}
+}
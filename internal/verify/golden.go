@@ -0,0 +1,154 @@
+package verify
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/sandinv/benchmark/internal/database"
+	"github.com/sandinv/benchmark/internal/stats"
+)
+
+// goldenHeader is the header row of golden files
+var goldenHeader = []string{"template", "key", "rows", "checksum"}
+
+// goldenKey identifies a result in a golden file
+type goldenKey struct {
+	template string
+	key      string // parameters bound by the template, as returned by database.Template.Key
+}
+
+// Golden verifies the results against a golden file.
+//
+// Golden files are CSV files with a header row and a row per query template and input record,
+// holding the number of rows and the checksum of the result in hexadecimal:
+//
+//	template,key,rows,checksum
+//	cpu_usage,hostname=host_000008 start_time=2017-01-01T08:59:22Z end_time=2017-01-01T09:59:22Z,60,5b1d8f0c2e9a7d43
+type Golden struct {
+	expected map[goldenKey]Expected
+}
+
+// ReadGolden reads a golden file written by a Recorder
+func ReadGolden(input io.Reader) (*Golden, error) {
+	reader := csv.NewReader(input)
+	reader.FieldsPerRecord = len(goldenHeader)
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("empty golden file")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading golden file: %w", err)
+	}
+	if !slices.Equal(header, goldenHeader) {
+		return nil, fmt.Errorf("invalid golden file header %q, expected %q", header, goldenHeader)
+	}
+
+	golden := &Golden{expected: make(map[goldenKey]Expected)}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return golden, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading golden file: %w", err)
+		}
+
+		rows, err := strconv.ParseInt(record[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number of rows %q in golden file: %w", record[2], err)
+		}
+		checksum, err := strconv.ParseUint(record[3], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid checksum %q in golden file: %w", record[3], err)
+		}
+		golden.expected[goldenKey{template: record[0], key: record[1]}] = Expected{Rows: rows, Checksum: checksum}
+	}
+}
+
+// Verify compares the result to the one of the golden file, the expected result being
+// unknown when the golden file has none for the template and parameters
+func (g *Golden) Verify(_ context.Context, template database.Template, params database.QueryParams, result database.Result) (stats.Verdict, string) {
+	key, err := template.Key(params)
+	if err != nil {
+		return stats.VerdictUnknown, err.Error()
+	}
+	expected, ok := g.expected[goldenKey{template: template.Name, key: key}]
+	if !ok {
+		return stats.VerdictUnknown, fmt.Sprintf("%s: missing from the golden file", key)
+	}
+	return compare(key, expected, result)
+}
+
+// Method returns "golden file"
+func (g *Golden) Method() string {
+	return "golden file"
+}
+
+// Recorder writes the results to a golden file, once per query template and input record.
+// It is safe for concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	writer  *csv.Writer
+	written map[goldenKey]bool
+}
+
+// NewRecorder creates a recorder writing a golden file to the provided output
+func NewRecorder(out io.Writer) *Recorder {
+	writer := csv.NewWriter(out)
+	_ = writer.Write(goldenHeader)
+	return &Recorder{writer: writer, written: make(map[goldenKey]bool)}
+}
+
+// Verify records the result, unless one was already recorded for the template and
+// parameters, and doesn't verify it
+func (r *Recorder) Verify(_ context.Context, template database.Template, params database.QueryParams, result database.Result) (stats.Verdict, string) {
+	key, err := template.Key(params)
+	if err != nil {
+		return stats.VerdictNone, ""
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := goldenKey{template: template.Name, key: key}
+	if r.written[id] {
+		return stats.VerdictNone, ""
+	}
+	r.written[id] = true
+	_ = r.writer.Write([]string{
+		template.Name,
+		key,
+		strconv.FormatInt(result.Rows, 10),
+		fmt.Sprintf("%016x", result.Checksum),
+	})
+	return stats.VerdictNone, ""
+}
+
+// Method returns an empty string, as the recorder doesn't verify the results
+func (r *Recorder) Method() string {
+	return ""
+}
+
+// Results returns the number of results recorded
+func (r *Recorder) Results() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.written)
+}
+
+// Flush writes any buffered result and reports any error writing the golden file
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.writer.Flush()
+	return r.writer.Error()
+}
@@ -0,0 +1,73 @@
+package verify
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sandinv/benchmark/internal/database"
+	"github.com/sandinv/benchmark/internal/stats"
+)
+
+func TestGolden(t *testing.T) {
+	start := time.Date(2017, 1, 1, 8, 59, 22, 0, time.UTC)
+	params := database.QueryParams{Hostname: "host_000001", StartTime: start, EndTime: start.Add(time.Hour)}
+	other := database.QueryParams{Hostname: "host_000002", StartTime: start, EndTime: start.Add(time.Hour)}
+	result := database.Result{Rows: 60, Checksum: 0x5b1d8f0c2e9a7d43}
+
+	var file strings.Builder
+	recorder := NewRecorder(&file)
+	ctx := context.Background()
+	if verdict, _ := recorder.Verify(ctx, database.DefaultTemplate, params, result); verdict != stats.VerdictNone {
+		t.Errorf("Expected the recorder not to verify the results, got verdict %v", verdict)
+	}
+	// Only the first result of a record is kept
+	recorder.Verify(ctx, database.DefaultTemplate, params, database.Result{Rows: 1})
+	if err := recorder.Flush(); err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+	if recorder.Results() != 1 {
+		t.Errorf("Expected 1 result recorded, got %d", recorder.Results())
+	}
+
+	want := "template,key,rows,checksum\n" +
+		"cpu_usage,hostname=host_000001 start_time=2017-01-01T08:59:22Z end_time=2017-01-01T09:59:22Z,60,5b1d8f0c2e9a7d43\n"
+	if file.String() != want {
+		t.Errorf("Expected golden file:\n%s\ngot:\n%s", want, file.String())
+	}
+
+	golden, err := ReadGolden(strings.NewReader(file.String()))
+	if err != nil {
+		t.Fatalf("ReadGolden() failed: %v", err)
+	}
+	if verdict, detail := golden.Verify(ctx, database.DefaultTemplate, params, result); verdict != stats.VerdictMatch {
+		t.Errorf("Expected a match, got verdict %v: %s", verdict, detail)
+	}
+	verdict, detail := golden.Verify(ctx, database.DefaultTemplate, params, database.Result{Rows: 58, Checksum: 1})
+	if verdict != stats.VerdictMismatch || !strings.Contains(detail, "expected 60 rows (checksum 5b1d8f0c2e9a7d43), got 58 rows") {
+		t.Errorf("Expected a mismatch, got verdict %v: %s", verdict, detail)
+	}
+	verdict, detail = golden.Verify(ctx, database.DefaultTemplate, other, result)
+	if verdict != stats.VerdictUnknown || !strings.Contains(detail, "missing from the golden file") {
+		t.Errorf("Expected an unknown result, got verdict %v: %s", verdict, detail)
+	}
+}
+
+func TestReadGoldenErrors(t *testing.T) {
+	tests := map[string]string{
+		"empty file":       "",
+		"invalid header":   "template,rows\n",
+		"invalid rows":     "template,key,rows,checksum\ncpu_usage,hostname=a,sixty,00\n",
+		"invalid checksum": "template,key,rows,checksum\ncpu_usage,hostname=a,60,xyz\n",
+		"missing field":    "template,key,rows,checksum\ncpu_usage,hostname=a,60\n",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ReadGolden(strings.NewReader(input)); err == nil {
+				t.Errorf("Expected an error for %q", input)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sandinv/benchmark/internal/database"
+	"github.com/sandinv/benchmark/internal/stats"
+)
+
+// Executor runs queries, such as a database.Database
+type Executor interface {
+	Execute(ctx context.Context, template database.Template, params database.QueryParams) (database.Result, error)
+}
+
+// Reference verifies the results against the ones of reference queries, run with the same
+// parameters after each benchmarked query. Reference queries are expected to return the same
+// rows in a naive way, e.g. scanning the hypertable instead of reading a continuous aggregate.
+type Reference struct {
+	executor   Executor
+	references map[string]database.Template // by name of the template they verify
+}
+
+// NewReference creates a verifier running the reference query with the name of each
+// template. It fails when a template has no reference query.
+func NewReference(executor Executor, templates, references []database.Template) (*Reference, error) {
+	r := &Reference{executor: executor, references: make(map[string]database.Template, len(references))}
+	for _, reference := range references {
+		r.references[reference.Name] = reference
+	}
+	for _, template := range templates {
+		if _, ok := r.references[template.Name]; !ok {
+			return nil, fmt.Errorf("no reference query for query %q", template.Name)
+		}
+	}
+	return r, nil
+}
+
+// Verify runs the reference query of the template and compares its result, the expected
+// result being unknown when the reference query fails
+func (r *Reference) Verify(ctx context.Context, template database.Template, params database.QueryParams, result database.Result) (stats.Verdict, string) {
+	key, err := template.Key(params)
+	if err != nil {
+		return stats.VerdictUnknown, err.Error()
+	}
+	expected, err := r.executor.Execute(ctx, r.references[template.Name], params)
+	if err != nil {
+		return stats.VerdictUnknown, fmt.Sprintf("%s: reference query failed: %v", key, err)
+	}
+	return compare(key, Expected{Rows: expected.Rows, Checksum: expected.Checksum}, result)
+}
+
+// Method returns "reference query"
+func (r *Reference) Method() string {
+	return "reference query"
+}
@@ -0,0 +1,59 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sandinv/benchmark/internal/database"
+	"github.com/sandinv/benchmark/internal/stats"
+)
+
+// fakeExecutor returns the same result for every query
+type fakeExecutor struct {
+	result database.Result
+	err    error
+	sql    string // query of the last template run
+}
+
+func (e *fakeExecutor) Execute(_ context.Context, template database.Template, _ database.QueryParams) (database.Result, error) {
+	e.sql = template.SQL
+	return e.result, e.err
+}
+
+func TestReference(t *testing.T) {
+	executor := &fakeExecutor{result: database.Result{Rows: 60, Checksum: 42}}
+	templates := []database.Template{database.DefaultTemplate}
+	reference, err := NewReference(executor, templates, []database.Template{database.DefaultReferenceTemplate})
+	if err != nil {
+		t.Fatalf("NewReference() failed: %v", err)
+	}
+
+	ctx := context.Background()
+	start := time.Date(2017, 1, 1, 8, 59, 22, 0, time.UTC)
+	params := database.QueryParams{Hostname: "host_000001", StartTime: start, EndTime: start.Add(time.Hour)}
+	if verdict, detail := reference.Verify(ctx, database.DefaultTemplate, params, database.Result{Rows: 60, Checksum: 42}); verdict != stats.VerdictMatch {
+		t.Errorf("Expected a match, got verdict %v: %s", verdict, detail)
+	}
+	if executor.sql != database.DefaultReferenceTemplate.SQL {
+		t.Errorf("Expected the reference query to be run, got %q", executor.sql)
+	}
+	if verdict, _ := reference.Verify(ctx, database.DefaultTemplate, params, database.Result{Rows: 60, Checksum: 7}); verdict != stats.VerdictMismatch {
+		t.Errorf("Expected a mismatch, got verdict %v", verdict)
+	}
+
+	executor.err = errors.New("connection reset by peer")
+	verdict, detail := reference.Verify(ctx, database.DefaultTemplate, params, database.Result{Rows: 60, Checksum: 42})
+	if verdict != stats.VerdictUnknown || !strings.Contains(detail, "reference query failed: connection reset by peer") {
+		t.Errorf("Expected an unknown result, got verdict %v: %s", verdict, detail)
+	}
+}
+
+func TestReferenceMissing(t *testing.T) {
+	templates := []database.Template{database.DefaultTemplate, {Name: "last_point"}}
+	if _, err := NewReference(&fakeExecutor{}, templates, []database.Template{database.DefaultReferenceTemplate}); err == nil {
+		t.Error("Expected an error for a query without reference query")
+	}
+}
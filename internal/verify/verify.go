@@ -0,0 +1,43 @@
+// Package verify checks the results of the benchmarked queries against their expected
+// results, so that a regressed index or a broken continuous aggregate returning fast but
+// wrong answers shows up in the report instead of improving the latency.
+//
+// The expected results are either read from a golden file recorded by a run known to be
+// correct, keyed by the parameters each input record binds, or obtained by running a naive
+// reference query returning the same rows. Results are compared by their number of rows and
+// by a checksum of their values, in order, which requires the database to compute checksums.
+package verify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sandinv/benchmark/internal/database"
+	"github.com/sandinv/benchmark/internal/stats"
+)
+
+// Verifier checks the results of the queries
+type Verifier interface {
+	// Verify returns the verdict of the result of the template run with the parameters,
+	// with a description of the mismatch or of why the expected result is unknown
+	Verify(ctx context.Context, template database.Template, params database.QueryParams, result database.Result) (stats.Verdict, string)
+
+	// Method describes how the expected results are obtained, e.g. "golden file",
+	// and is empty for the verifiers that only record the results
+	Method() string
+}
+
+// Expected is the expected result of a query
+type Expected struct {
+	Rows     int64
+	Checksum uint64
+}
+
+// compare returns the verdict of the result of the query identified by the key
+func compare(key string, expected Expected, result database.Result) (stats.Verdict, string) {
+	if result.Rows == expected.Rows && result.Checksum == expected.Checksum {
+		return stats.VerdictMatch, ""
+	}
+	return stats.VerdictMismatch, fmt.Sprintf("%s: expected %d rows (checksum %016x), got %d rows (checksum %016x)",
+		key, expected.Rows, expected.Checksum, result.Rows, result.Checksum)
+}
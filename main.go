@@ -3,10 +3,27 @@
 //
 // The tool supports:
 //   - Concurrent query execution with configurable worker count
-//   - Streaming CSV input processing from file or stdin
+//   - Staged load profiles changing the number of workers over time
+//   - Worker-count sweeps recommending a concurrency within a latency SLO
+//   - Closed-loop or open-loop load at a fixed arrival rate
+//   - Replay of the input at its original timing with a speed multiplier
+//   - Custom SQL query templates with named placeholders mapped to CSV columns
+//   - Streaming CSV or JSON Lines input processing from file or stdin
+//   - Duration or iteration bounded runs looping over the input
 //   - Graceful shutdown via signal handling (SIGINT, SIGTERM)
+//   - Configurable timestamp formats and time zone
 //   - Strict mode for data validation
-//   - Comprehensive statistics with percentiles (P90, P95, P99)
+//   - Configurable query timeout and retries of transient errors with backoff
+//   - Warm-up phase reported apart from the statistics
+//   - Server-side planning and execution time of a sample of the queries with EXPLAIN ANALYZE
+//   - Comprehensive statistics with configurable percentiles (P90, P95, P99 by default)
+//   - Bounded-memory latency histograms with configurable precision
+//   - Verification of the results against a golden file or naive reference queries
+//   - Time to first row and fetch time of the queries, with the rows and bytes returned
+//   - Per-hostname latency breakdown listing the slowest hosts
+//   - Throughput and per-interval timeline of the run
+//   - Progress lines on stderr and Prometheus metrics while the benchmark runs
+//   - Text or JSON report output
 //
 // Usage:
 //
@@ -19,23 +36,81 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
+	// Embed the time zone database so -timezone works on images without tzdata
+	_ "time/tzdata"
 
 	"log"
 
 	"github.com/sandinv/benchmark/internal/benchmark"
 	"github.com/sandinv/benchmark/internal/database"
+	"github.com/sandinv/benchmark/internal/metrics"
+	"github.com/sandinv/benchmark/internal/parser"
+	"github.com/sandinv/benchmark/internal/stats"
+	"github.com/sandinv/benchmark/internal/verify"
 )
 
 type Config struct {
-	DatabaseConn string
-	Workers      int
-	InputFile    string
-	StrictMode   bool
+	DatabaseConn     string
+	Workers          int
+	Profile          string
+	ProfileFile      string
+	Sweep            string
+	SLO              time.Duration
+	SLOPercentile    float64
+	SLOErrorRate     float64
+	Rate             float64
+	Replay           float64
+	ReplayInterval   time.Duration
+	InputFile        string
+	Duration         time.Duration
+	Iterations       int
+	Format           string
+	StrictMode       bool
+	Output           string
+	TopHosts         int
+	Interval         time.Duration
+	Timeline         bool
+	TimelineFile     string
+	Progress         time.Duration
+	MetricsAddr      string
+	QueryTimeout     time.Duration
+	StatementTimeout time.Duration
+	MaxAttempts      int
+	RetryBackoff     time.Duration
+	RetryMaxBackoff  time.Duration
+	RetryOn          string
+	ExplainSample    float64
+	Verify           string
+	GoldenFile       string
+	ReferenceFile    string
+	Warmup           string
+	Precision        int
+	Percentiles      string
+	QueryFile        string
+	TimeFormat       string
+	Timezone         string
 }
 
+// Supported report output formats
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// Supported verification modes
+const (
+	verifyGolden    = "golden"    // compare the results to a golden file
+	verifyRecord    = "record"    // write the results to a golden file
+	verifyReference = "reference" // compare the results to the ones of reference queries
+)
+
 func init() {
 	// Override default usage output
 	flag.Usage = printUsage
@@ -49,15 +124,193 @@ func main() {
 		log.Fatalf("workers should be equal or greater than 1")
 	}
 
+	if config.Profile != "" && config.ProfileFile != "" {
+		log.Fatalf("profile and profileFile can't be used together")
+	}
+
+	if config.Sweep != "" && (config.Profile != "" || config.ProfileFile != "") {
+		log.Fatalf("sweep and profile can't be used together")
+	}
+
+	if config.Sweep != "" && config.InputFile == "" {
+		log.Fatalf("sweep requires an inputFile, as the input is read once per number of workers")
+	}
+
+	if config.SLO < 0 {
+		log.Fatalf("slo should be equal or greater than 0")
+	}
+
+	if config.SLOPercentile <= 0 || config.SLOPercentile > 100 {
+		log.Fatalf("sloPercentile should be greater than 0 and lower or equal than 100")
+	}
+
+	if config.SLOErrorRate < 0 || config.SLOErrorRate > 1 {
+		log.Fatalf("sloErrorRate should be between 0 and 1")
+	}
+
+	if config.Rate < 0 {
+		log.Fatalf("rate should be equal or greater than 0")
+	}
+
+	if config.Replay < 0 {
+		log.Fatalf("replay should be equal or greater than 0")
+	}
+
+	if config.Replay > 0 && config.Rate > 0 {
+		log.Fatalf("rate and replay can't be used together")
+	}
+
+	if config.Duration < 0 {
+		log.Fatalf("duration should be equal or greater than 0")
+	}
+
+	if config.Iterations < 0 {
+		log.Fatalf("iterations should be equal or greater than 0")
+	}
+
+	looping := config.Duration > 0 || config.Iterations > 1
+	if looping && config.Replay > 0 {
+		log.Fatalf("replay can't loop over the input, remove duration and iterations")
+	}
+
+	if looping && config.Sweep != "" {
+		log.Fatalf("sweep can't loop over the input, remove duration and iterations")
+	}
+
+	if config.Format != parser.FormatCSV && config.Format != parser.FormatJSONL {
+		log.Fatalf("format should be either %q or %q", parser.FormatCSV, parser.FormatJSONL)
+	}
+
+	warmup, err := benchmark.ParseWarmup(config.Warmup)
+	if err != nil {
+		log.Fatalf("invalid warmup: %s", err)
+	}
+
+	if config.Precision != 0 && (config.Precision < stats.MinPrecision || config.Precision > stats.MaxPrecision) {
+		log.Fatalf("precision should be 0 or between %d and %d", stats.MinPrecision, stats.MaxPrecision)
+	}
+
+	percentiles, err := stats.ParsePercentiles(config.Percentiles)
+	if err != nil {
+		log.Fatalf("invalid percentiles: %s", err)
+	}
+
+	if config.Output != outputText && config.Output != outputJSON {
+		log.Fatalf("output should be either %q or %q", outputText, outputJSON)
+	}
+
+	if config.Interval <= 0 {
+		log.Fatalf("interval should be greater than 0")
+	}
+
+	if config.QueryTimeout <= 0 {
+		log.Fatalf("queryTimeout should be greater than 0")
+	}
+
+	if config.StatementTimeout < 0 {
+		log.Fatalf("statementTimeout should be equal or greater than 0")
+	}
+
+	if config.MaxAttempts < 1 {
+		log.Fatalf("maxAttempts should be equal or greater than 1")
+	}
+
+	if config.RetryBackoff <= 0 || config.RetryMaxBackoff < config.RetryBackoff {
+		log.Fatalf("retryBackoff should be greater than 0 and not greater than retryMaxBackoff")
+	}
+
+	retryClasses, err := database.ParseErrorClasses(config.RetryOn)
+	if err != nil {
+		log.Fatalf("invalid retryOn: %s", err)
+	}
+
+	if config.Progress < 0 {
+		log.Fatalf("progress should be equal or greater than 0")
+	}
+
+	if config.ExplainSample < 0 || config.ExplainSample > 1 {
+		log.Fatalf("explainSample should be between 0 and 1")
+	}
+
+	switch config.Verify {
+	case "":
+	case verifyGolden, verifyRecord:
+		if config.GoldenFile == "" {
+			log.Fatalf("verify %s requires a goldenFile", config.Verify)
+		}
+	case verifyReference:
+	default:
+		log.Fatalf("verify should be either %q, %q or %q", verifyGolden, verifyRecord, verifyReference)
+	}
+
+	if config.ReferenceFile != "" && config.Verify != verifyReference {
+		log.Fatalf("referenceFile requires verify %s", verifyReference)
+	}
+
 	parseConnectionString(&config)
 
+	timeParser, err := parser.NewTimeParser(config.TimeFormat, config.Timezone)
+	if err != nil {
+		log.Fatalf("invalid time settings: %s", err)
+	}
+
+	stages, err := parseProfile(config.Profile, config.ProfileFile)
+	if err != nil {
+		log.Fatalf("invalid load profile: %s", err)
+	}
+
+	templates, err := parseQueryFile(config.QueryFile)
+	if err != nil {
+		log.Fatalf("couldn't read query file: %s", err)
+	}
+
+	var workerCounts []int
+	if config.Sweep != "" {
+		if workerCounts, err = benchmark.ParseWorkerCounts(config.Sweep); err != nil {
+			log.Fatalf("invalid sweep: %s", err)
+		}
+	}
+
 	reader, closeFun, err := parseInputFile(config.InputFile)
 	if err != nil {
 		log.Fatalf("couldn't read input file: %s", err)
 	}
 	defer closeFun()
 
-	db, err := database.Connect(config.DatabaseConn)
+	var source parser.Source
+	var loop *parser.LoopSource
+	var input *parser.CountingReader
+	if looping {
+		loop, input, err = parseLoopingInput(reader, config, timeParser)
+		source = loop
+	} else {
+		input = parser.NewCountingReader(reader, inputSize(reader))
+		source, err = parser.NewSource(config.Format, input, config.StrictMode, timeParser)
+	}
+	if err != nil {
+		log.Fatalf("invalid input: %s", err)
+	}
+
+	// The queries binding a column missing from the input would all fail
+	checkedTemplates := templates
+	if len(checkedTemplates) == 0 {
+		checkedTemplates = []database.Template{database.DefaultTemplate}
+	}
+	if err := parser.CheckColumns(source, checkedTemplates); err != nil {
+		log.Fatalf("invalid input: %s", err)
+	}
+
+	db, err := database.Connect(config.DatabaseConn, database.Options{
+		QueryTimeout:     config.QueryTimeout,
+		StatementTimeout: config.StatementTimeout,
+		Retry: database.RetryPolicy{
+			MaxAttempts: config.MaxAttempts,
+			Backoff:     config.RetryBackoff,
+			MaxBackoff:  config.RetryMaxBackoff,
+			Classes:     retryClasses,
+		},
+		Checksum: config.Verify != "",
+	})
 	if err != nil {
 		log.Fatalf("can't establish a connection with the database %s", err)
 	}
@@ -68,11 +321,73 @@ func main() {
 
 	setupShutdown(cancel)
 
-	runner := benchmark.NewRunner(db, config.Workers, config.StrictMode)
-	stats, err := runner.Run(ctx, reader)
+	runnerConfig := benchmark.Config{
+		Workers:        config.Workers,
+		Stages:         stages,
+		Rate:           config.Rate,
+		ReplaySpeed:    config.Replay,
+		ReplayInterval: config.ReplayInterval,
+		Warmup:         warmup,
+		Precision:      config.Precision,
+		Percentiles:    percentiles,
+		Interval:       config.Interval,
+		Progress:       config.Progress,
+		ExplainRate:    config.ExplainSample,
+		Templates:      templates,
+	}
+
+	verifier, finishVerification, err := newVerifier(config, db, templates)
+	if err != nil {
+		log.Fatalf("couldn't set up the verification: %s", err)
+	}
+	runnerConfig.Verifier = verifier
+
+	if config.MetricsAddr != "" {
+		runnerConfig.Metrics = metrics.New()
+		if err := serveMetrics(config.MetricsAddr, runnerConfig.Metrics); err != nil {
+			log.Fatalf("couldn't serve metrics: %s", err)
+		}
+	}
+
+	if workerCounts != nil {
+		runSweep(ctx, db, runnerConfig, workerCounts, config, timeParser)
+		if err := finishVerification(); err != nil {
+			log.Fatalf("couldn't write golden file: %s", err)
+		}
+		return
+	}
+
+	runnerConfig.Input = input
+	runner := benchmark.NewRunner(db, runnerConfig)
+	stats, err := runner.Run(ctx, source)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if err := finishVerification(); err != nil {
+		log.Fatalf("couldn't write golden file: %s", err)
+	}
+
+	stats.Metadata.InputFile = config.InputFile
+	stats.Metadata.QueryTimeout = config.QueryTimeout
+	stats.Metadata.StatementTimeout = config.StatementTimeout
+	if loop != nil {
+		stats.Metadata.Iterations = loop.Passes()
+	}
+	stats.TopHosts = config.TopHosts
+	stats.PrintTimeline = config.Timeline
+
+	if config.TimelineFile != "" {
+		if err := writeTimeline(config.TimelineFile, stats); err != nil {
+			log.Fatalf("couldn't write timeline: %s", err)
+		}
+	}
+
+	if config.Output == outputJSON {
+		if err := stats.WriteJSON(os.Stdout); err != nil {
+			log.Fatalf("couldn't write JSON report: %s", err)
+		}
+		return
+	}
 
 	stats.Print(os.Stdout)
 
@@ -83,8 +398,43 @@ func parseFlags() Config {
 	config := Config{}
 
 	flag.IntVar(&config.Workers, "workers", 5, "number of concurrent workers (should be equal or greater than 1)")
-	flag.StringVar(&config.InputFile, "inputFile", "", "CSV file path ( if not provided, reads from stdin")
-	flag.BoolVar(&config.StrictMode, "strict", false, "strict mode: exit on any CSV reading or parsing error (default: false)")
+	flag.StringVar(&config.Profile, "profile", "", "load profile overriding -workers: comma separated workers:duration stages, e.g. 2:30s,4:30s,8:30s (the last duration may be omitted to run until the end of the input)")
+	flag.StringVar(&config.ProfileFile, "profileFile", "", "file with the load profile, one workers:duration stage per line")
+	flag.StringVar(&config.Sweep, "sweep", "", "sweep mode: run the input once per number of workers and compare them, e.g. 1,2,4,8,16 or 1-8 (requires -inputFile)")
+	flag.DurationVar(&config.SLO, "slo", 0, "sweep mode: latency SLO used to recommend a number of workers, e.g. 50ms (0 recommends the highest throughput)")
+	flag.Float64Var(&config.SLOPercentile, "sloPercentile", 99, "sweep mode: percentile the latency SLO applies to")
+	flag.Float64Var(&config.SLOErrorRate, "sloErrorRate", 0.01, "sweep mode: highest share of failed queries of the recommended number of workers, e.g. 0.01 for 1%")
+	flag.Float64Var(&config.Rate, "rate", 0, "open-loop mode: records sent per second regardless of the query latency (0 runs a closed loop)")
+	flag.Float64Var(&config.Replay, "replay", 0, "replay mode: send the records at their original timing, taken from the issue_time column, sped up by the factor, e.g. 2 or 10 (0 disables the replay)")
+	flag.DurationVar(&config.ReplayInterval, "replayInterval", benchmark.DefaultReplayInterval, "replay mode: original spacing of the records without issue_time, in row order")
+	flag.StringVar(&config.InputFile, "inputFile", "", "input file path ( if not provided, reads from stdin")
+	flag.DurationVar(&config.Duration, "duration", 0, "run for the duration, looping over the input as many times as needed, e.g. 10m (0 stops at the end of the input)")
+	flag.IntVar(&config.Iterations, "iterations", 0, "number of passes over the input (default 1, or unlimited with -duration)")
+	flag.StringVar(&config.Format, "format", parser.FormatCSV, "input format: csv or jsonl (JSON Lines)")
+	flag.BoolVar(&config.StrictMode, "strict", false, "strict mode: exit on any input reading or parsing error (default: false)")
+	flag.StringVar(&config.Warmup, "warmup", "", "warm-up phase recorded apart from the statistics: a number of queries, e.g. 500, or a duration, e.g. 30s")
+	flag.DurationVar(&config.QueryTimeout, "queryTimeout", database.DefaultQueryTimeout, "time after which a query is cancelled and counted as timed out")
+	flag.DurationVar(&config.StatementTimeout, "statementTimeout", 0, "statement_timeout set on every database session so that the server aborts longer queries, e.g. 3s (0 keeps the server setting)")
+	flag.IntVar(&config.MaxAttempts, "maxAttempts", 1, "attempts per query, retrying the queries failing with a transient error (1 disables the retries)")
+	flag.DurationVar(&config.RetryBackoff, "retryBackoff", database.DefaultRetryBackoff, "backoff before the first retry, doubled for each retry up to -retryMaxBackoff")
+	flag.DurationVar(&config.RetryMaxBackoff, "retryMaxBackoff", database.DefaultRetryMaxBackoff, "upper bound of the backoff between retries")
+	flag.StringVar(&config.RetryOn, "retryOn", strings.Join(database.DefaultRetryableClasses, ","), "comma separated list of the error classes retried: network, deadline_exceeded, canceled, other or sqlstate_<code>")
+	flag.Float64Var(&config.ExplainSample, "explainSample", 0, "share of the queries run with EXPLAIN (ANALYZE, BUFFERS) to report their planning and execution time, chunks scanned and buffer hits, e.g. 0.01 for 1% (0 disables the sampling)")
+	flag.StringVar(&config.Verify, "verify", "", "verification mode: golden compares the results to -goldenFile, record writes them to -goldenFile and reference compares them to the ones of reference queries (if not provided, results are not verified)")
+	flag.StringVar(&config.GoldenFile, "goldenFile", "", "golden file with the expected results, keyed by query and input record")
+	flag.StringVar(&config.ReferenceFile, "referenceFile", "", "file with the reference query of each query template, by name (if not provided, the built-in cpu_usage query is compared to a naive date_trunc query)")
+	flag.IntVar(&config.Precision, "precision", stats.DefaultPrecision, "significant digits kept by the latency histograms (1-5), 0 stores every duration for exact statistics")
+	flag.StringVar(&config.QueryFile, "queryFile", "", "file with one or more SQL query templates (if not provided, runs the built-in cpu_usage query)")
+	flag.StringVar(&config.TimeFormat, "timeFormat", parser.TimeFormatDefault, "format of the time columns: default (2006-01-02 15:04:05), rfc3339, unix, unixms, auto or a Go time layout")
+	flag.StringVar(&config.Timezone, "timezone", "UTC", "time zone of the times without offset, e.g. Europe/Madrid")
+	flag.StringVar(&config.Percentiles, "percentiles", "90,95,99", "comma separated list of percentiles to report, e.g. 50,99.9,99.99")
+	flag.StringVar(&config.Output, "output", outputText, "report output format: text or json")
+	flag.IntVar(&config.TopHosts, "topHosts", stats.DefaultTopHosts, "number of slowest hosts listed in the text report (0 disables the section)")
+	flag.DurationVar(&config.Interval, "interval", stats.DefaultInterval, "width of the intervals of the timeline, e.g. 1s or 1m")
+	flag.BoolVar(&config.Timeline, "timeline", false, "add the timeline, with the queries, errors, median and P99 of every interval, to the text report")
+	flag.StringVar(&config.TimelineFile, "timelineFile", "", "file to write the timeline to as CSV")
+	flag.DurationVar(&config.Progress, "progress", 0, "interval between the progress lines printed to stderr during the run, e.g. 10s (0 disables them)")
+	flag.StringVar(&config.MetricsAddr, "metrics-addr", "", "address to serve Prometheus metrics on at /metrics during the run, e.g. :9090 (if not provided, metrics are not served)")
 
 	flag.Parse()
 
@@ -124,6 +474,125 @@ func parseInputFile(filepath string) (io.Reader, func(), error) {
 
 }
 
+// runSweep runs the benchmark over the input file once per number of workers and writes the sweep report
+func runSweep(ctx context.Context, db *database.Database, runnerConfig benchmark.Config, workerCounts []int, config Config, timeParser *parser.TimeParser) {
+
+	open := func() (parser.Source, io.Closer, error) {
+		inputF, err := os.Open(config.InputFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		source, err := parser.NewSource(config.Format, inputF, config.StrictMode, timeParser)
+		if err != nil {
+			_ = inputF.Close()
+			return nil, nil, err
+		}
+		return source, inputF, nil
+	}
+
+	sweep := stats.NewSweep(config.SLO, config.SLOPercentile, config.SLOErrorRate)
+	if err := benchmark.Sweep(ctx, db, runnerConfig, workerCounts, open, sweep); err != nil {
+		if len(sweep.Points) == 0 {
+			log.Fatal(err)
+		}
+		log.Printf("Sweep interrupted: %s", err)
+	}
+
+	if config.Output == outputJSON {
+		if err := sweep.WriteJSON(os.Stdout); err != nil {
+			log.Fatalf("couldn't write JSON report: %s", err)
+		}
+		return
+	}
+
+	sweep.Print(os.Stdout)
+}
+
+// parseProfile parses the load profile from the spec or the file, if any
+func parseProfile(spec, filepath string) ([]benchmark.Stage, error) {
+
+	if spec != "" {
+		return benchmark.ParseProfile(spec)
+	}
+	if filepath == "" {
+		return nil, nil
+	}
+
+	profileF, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = profileF.Close()
+	}()
+
+	return benchmark.ReadProfile(profileF)
+}
+
+// maxBufferedInput is the size of the largest stdin input buffered to loop over it
+const maxBufferedInput = 256 << 20
+
+// parseLoopingInput creates a source looping over the input for the configured duration or
+// iterations, and the reader counting the bytes read over every pass. Inputs that can't be
+// rewound, such as piped stdin, are buffered in memory.
+func parseLoopingInput(reader io.Reader, config Config, timeParser *parser.TimeParser) (*parser.LoopSource, *parser.CountingReader, error) {
+
+	rewindable, err := parser.Rewindable(reader, maxBufferedInput)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The size of the input is only known to be read as many times as iterations
+	var total int64
+	if config.Iterations > 0 {
+		size, err := rewindable.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, nil, err
+		}
+		total = size * int64(config.Iterations)
+	}
+	input := parser.NewCountingReader(rewindable, total)
+
+	loop, err := parser.NewLoopSource(func() (parser.Source, error) {
+		if _, err := input.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return parser.NewSource(config.Format, input, config.StrictMode, timeParser)
+	}, config.Iterations, config.Duration)
+	return loop, input, err
+}
+
+// inputSize returns the size of the input when it is a regular file, 0 otherwise
+func inputSize(reader io.Reader) int64 {
+	f, ok := reader.(*os.File)
+	if !ok {
+		return 0
+	}
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return 0
+	}
+	return info.Size()
+}
+
+// parseQueryFile reads the query templates from the file, if any
+func parseQueryFile(filepath string) ([]database.Template, error) {
+
+	if filepath == "" {
+		return nil, nil
+	}
+
+	queryF, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = queryF.Close()
+	}()
+
+	return database.ParseTemplates(queryF)
+}
+
 func setupShutdown(cancel func()) {
 	// Setup signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -138,6 +607,92 @@ func setupShutdown(cancel func()) {
 
 }
 
+// serveMetrics serves the metrics of the registry at /metrics on the address in the background
+func serveMetrics(addr string, registry *metrics.Registry) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", registry)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			log.Printf("Metrics server stopped: %v", err)
+		}
+	}()
+
+	log.Printf("Serving metrics on http://%s/metrics", listener.Addr())
+	return nil
+}
+
+// newVerifier creates the verifier of the verification mode, nil when the results are not
+// verified, and the function to call once the run is over, which writes the golden file
+// when recording one
+func newVerifier(config Config, db *database.Database, templates []database.Template) (verify.Verifier, func() error, error) {
+	finish := func() error { return nil }
+
+	switch config.Verify {
+	case verifyGolden:
+		f, err := os.Open(config.GoldenFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		golden, err := verify.ReadGolden(f)
+		return golden, finish, err
+
+	case verifyRecord:
+		f, err := os.Create(config.GoldenFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		recorder := verify.NewRecorder(f)
+		finish = func() error {
+			if err := recorder.Flush(); err != nil {
+				_ = f.Close()
+				return err
+			}
+			log.Printf("Recorded %d results to %s", recorder.Results(), config.GoldenFile)
+			return f.Close()
+		}
+		return recorder, finish, nil
+
+	case verifyReference:
+		references := []database.Template{database.DefaultReferenceTemplate}
+		if config.ReferenceFile != "" {
+			var err error
+			if references, err = parseQueryFile(config.ReferenceFile); err != nil {
+				return nil, nil, fmt.Errorf("couldn't read reference file: %w", err)
+			}
+		}
+		if len(templates) == 0 {
+			templates = []database.Template{database.DefaultTemplate}
+		}
+		reference, err := verify.NewReference(db, templates, references)
+		return reference, finish, err
+
+	default:
+		return nil, finish, nil
+	}
+}
+
+// writeTimeline writes the timeline of the statistics as CSV to the file
+func writeTimeline(filepath string, statistics *stats.Statistics) error {
+	f, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	if err := statistics.WriteTimelineCSV(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 // printUsage prints usage information
 func printUsage() {
 	fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", os.Args[0])
@@ -151,4 +706,22 @@ func printUsage() {
 	fmt.Fprintf(os.Stderr, "  %s -inputFile query_params.csv -workers 4\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  cat query_params.csv | %s -workers 4\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  %s -inputFile query_params.csv -workers 10 -strict\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -inputFile query_params.csv -workers 4 -duration 10m -warmup 30s\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -inputFile query_params.csv -workers 4 -progress 10s\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -inputFile query_params.csv -workers 8 -duration 1h -metrics-addr :9090\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -inputFile query_params.csv -profile 2:30s,4:30s,8:30s,16:30s\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -inputFile query_params.csv -sweep 1,2,4,8,16 -slo 50ms\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -inputFile query_params.csv -workers 16 -rate 200\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -inputFile dashboard_traffic.csv -workers 16 -replay 10\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -inputFile query_params.csv -workers 4 -output json > report.json\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -inputFile query_params.csv -workers 4 -timeline -timelineFile timeline.csv\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -inputFile query_params.csv -workers 4 -percentiles 50,99.9,99.99\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -inputFile query_params.csv -workers 4 -queryFile dashboards.sql\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -inputFile query_params.csv -workers 4 -queryTimeout 30s -statementTimeout 30s\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -inputFile query_params.csv -workers 4 -maxAttempts 3 -retryBackoff 50ms\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -inputFile query_params.csv -workers 4 -explainSample 0.01\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -inputFile query_params.csv -workers 4 -verify record -goldenFile golden.csv\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -inputFile query_params.csv -workers 4 -verify golden -goldenFile golden.csv\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -inputFile query_params.jsonl -format jsonl -workers 4\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -inputFile traffic.csv -workers 4 -timeFormat auto -timezone Europe/Madrid\n", os.Args[0])
 }